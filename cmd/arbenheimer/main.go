@@ -0,0 +1,352 @@
+// Command arbenheimer runs the gRPC server and an updater for every exchange in trading_pairs.yaml in one process.
+// Markets are stored in memory by default, so neither Redis nor Docker is needed.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/domain/usecases"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/binance"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/bybit"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/coinbase"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/feesfile"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/kraken"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/kucoin"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/monitoring"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/okx"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/pairsfile"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/server"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/updater"
+	"github.com/peterstirrup/arbenheimer/internal/outbound/file"
+	"github.com/peterstirrup/arbenheimer/internal/outbound/memory"
+	"github.com/peterstirrup/arbenheimer/internal/outbound/redis"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// feesFile sets the fee tier of each exchange.
+	feesFile = "data/fees.yaml"
+
+	// tradingPairsFile lists the trading pairs of each exchange. It's watched, so pairs can be changed while running.
+	tradingPairsFile = "data/trading_pairs.yaml"
+)
+
+type cliArgs struct {
+	updater.FeedArgs
+	BinanceAPIKey        string        `arg:"env:BINANCE_API_KEY" help:"Binance is skipped if not set"`
+	BinanceHostname      string        `arg:"env:BINANCE_HOSTNAME" default:"https://api.binance.com"`
+	BinanceWebsocketURL  string        `arg:"env:BINANCE_WEBSOCKET_URL" default:"wss://stream.binance.com:9443/ws/"`
+	BybitHostname        string        `arg:"env:BYBIT_HOSTNAME" default:"https://api.bybit.com"`
+	BybitWebsocketURL    string        `arg:"env:BYBIT_WEBSOCKET_URL" default:"wss://stream.bybit.com/v5/public/spot"`
+	CoinbaseHostname     string        `arg:"env:COINBASE_HOSTNAME" default:"https://api.exchange.coinbase.com"`
+	CoinbaseWebsocketURL string        `arg:"env:COINBASE_WEBSOCKET_URL" default:"wss://ws-feed.exchange.coinbase.com"`
+	History              string        `arg:"--history,env:HISTORY" default:"none" help:"none, redis or file"`
+	HistoryDir           string        `arg:"--history-dir,env:HISTORY_DIR" default:"history"`
+	Host                 string        `arg:"--host,env:HOST" default:"localhost"`
+	HTTPClientTimeout    time.Duration `arg:"env:HTTP_CLIENT_TIMEOUT" default:"10s"`
+	InstrumentsRefresh   time.Duration `arg:"--instruments-refresh,env:INSTRUMENTS_REFRESH" default:"1h" help:"how often order constraints are fetched from the exchanges"`
+	KrakenHostname       string        `arg:"env:KRAKEN_HOSTNAME" default:"https://api.kraken.com"`
+	KrakenWebsocketURL   string        `arg:"env:KRAKEN_WEBSOCKET_URL" default:"wss://ws.kraken.com/v2"`
+	KuCoinHostname       string        `arg:"env:KUCOIN_HOSTNAME" default:"https://api.kucoin.com"`
+	LogLevel             string        `arg:"--log-level,env:LOG_LEVEL" default:"debug"`
+	MetricsPort          int           `arg:"--metrics-port,env:METRICS_PORT" default:"9090"`
+	OKXHostname          string        `arg:"env:OKX_HOSTNAME" default:"https://www.okx.com"`
+	OKXWebsocketURL      string        `arg:"env:OKX_WEBSOCKET_URL" default:"wss://ws.okx.com:8443/ws/v5/public"`
+	Port                 int           `arg:"env:PORT" default:"9000"`
+	RedisHost            string        `arg:"--redis-host,env:REDIS_HOST"`
+	RedisPort            string        `arg:"--redis-port,env:REDIS_PORT"`
+	ShutdownDelay        time.Duration `arg:"--shutdown-delay,env:SHUTDOWN_DELAY" default:"5s" help:"how long health checks report not serving before the server stops, so load balancers stop sending it requests"`
+	StaleAfter           time.Duration `arg:"--stale-after,env:STALE_AFTER" default:"1m" help:"age after which a quote is stale"`
+	Store                string        `arg:"--store,env:STORE" default:"memory" help:"memory or redis"`
+	Tracing              string        `arg:"--tracing,env:TRACING" default:"none" help:"none, stdout or otlp"`
+	TracingSampleRatio   float64       `arg:"--tracing-sample-ratio,env:TRACING_SAMPLE_RATIO" default:"1" help:"fraction of traces recorded"`
+}
+
+// storeBroker stores and publishes markets, and stores their candles.
+type storeBroker interface {
+	usecases.Broker
+	usecases.CandleStore
+	usecases.Store
+}
+
+func main() {
+	var args cliArgs
+	arg.MustParse(&args)
+
+	if err := run(args); err != nil {
+		log.Fatal().Err(err).Msg("Failed to run arbenheimer")
+	}
+}
+
+// run serves the gRPC API and runs the updaters until SIGINT or SIGTERM. Returns an error if it can't be started.
+func run(args cliArgs) error {
+	logLevel, err := zerolog.ParseLevel(args.LogLevel)
+	if err != nil {
+		log.Warn().Msg("Failed to parse log level, defaulting to debug")
+		logLevel = zerolog.DebugLevel
+	}
+	zerolog.SetGlobalLevel(logLevel)
+
+	// Cancelled on SIGINT or SIGTERM, e.g. when a deploy stops the container
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := monitoring.SetupTracing(ctx, monitoring.TracingConfig{
+		Exporter:    args.Tracing,
+		SampleRatio: args.TracingSampleRatio,
+		ServiceName: "arbenheimer",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error().Err(err).Msg("Failed to shut down tracing")
+		}
+	}()
+
+	exchanges, err := pairsfile.Exchanges(tradingPairsFile)
+	if err != nil {
+		return fmt.Errorf("failed to get exchanges: %w", err)
+	}
+
+	pairs, err := pairsfile.Load(tradingPairsFile, "")
+	if err != nil {
+		return fmt.Errorf("failed to get trading pairs: %w", err)
+	}
+
+	fees, err := feesfile.Load(feesFile, exchanges)
+	if err != nil {
+		return fmt.Errorf("failed to get fee schedule: %w", err)
+	}
+
+	sb, err := newStoreBroker(args)
+	if err != nil {
+		return fmt.Errorf("failed to create store: %w", err)
+	}
+
+	history, err := newHistory(args)
+	if err != nil {
+		return fmt.Errorf("failed to create history: %w", err)
+	}
+
+	instruments := newInstruments(args)
+	go func() {
+		if err := instruments.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Err(err).Msg("Failed to run instruments")
+		}
+	}()
+
+	u := usecases.NewMarket(usecases.MarketConfig{
+		Broker:       sb,
+		Candles:      sb,
+		Fees:         fees,
+		History:      history,
+		Instruments:  instruments,
+		StaleAfter:   args.StaleAfter,
+		Store:        sb,
+		TimeNow:      time.Now,
+		TradingPairs: pairs,
+	})
+
+	var updaters sync.WaitGroup
+	// Updaters return once their last update has been written, so nothing is lost closing the store and history
+	defer func() {
+		stop()
+		updaters.Wait()
+		closeAll(sb, history)
+	}()
+
+	mw := pairsfile.NewWatcher(pairsfile.WatcherConfig{Path: tradingPairsFile, Updater: u})
+	go func() {
+		if err := mw.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Err(err).Msg("Failed to watch trading pairs")
+		}
+	}()
+
+	var checks []monitoring.HealthCheck
+	if p, ok := sb.(interface{ Ping(context.Context) error }); ok {
+		checks = append(checks, monitoring.HealthCheck{Name: "redis", Kind: monitoring.Readiness, Check: p.Ping})
+	}
+
+	for _, e := range exchanges {
+		if e == entities.ExchangeBinance && args.BinanceAPIKey == "" {
+			log.Warn().Msg("BINANCE_API_KEY not set, skipping Binance")
+			continue
+		}
+
+		exPairs, err := pairsfile.Load(tradingPairsFile, e)
+		if err != nil {
+			return fmt.Errorf("failed to get trading pairs for %s: %w", e, err)
+		}
+
+		ex, err := newExchange(args, e)
+		if err != nil {
+			return fmt.Errorf("failed to create %s exchange: %w", e, err)
+		}
+
+		up, err := args.NewClient(ex, exPairs, u)
+		if err != nil {
+			return fmt.Errorf("failed to create %s updater: %w", e, err)
+		}
+
+		// An exchange disconnecting or dying shouldn't restart the process and interrupt the others, so unlike the
+		// per-exchange updaters, a dead feed is only reported
+		checks = append(checks,
+			monitoring.HealthCheck{Name: "websocket/" + e.String(), Kind: monitoring.Informational, Check: up.CheckConnected},
+			monitoring.HealthCheck{Name: "feed/" + e.String(), Kind: monitoring.Informational, Check: up.CheckAlive},
+		)
+
+		// An exchange failing shouldn't take the others down with it
+		updaters.Add(1)
+		go func() {
+			defer updaters.Done()
+
+			if err := up.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Err(err).Str("exchange", e.String()).Msg("Failed to run updater")
+			}
+		}()
+
+		pw := pairsfile.NewWatcher(pairsfile.WatcherConfig{Exchange: e, Path: tradingPairsFile, Updater: up})
+		go func() {
+			if err := pw.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Err(err).Str("exchange", e.String()).Msg("Failed to watch trading pairs")
+			}
+		}()
+	}
+
+	gs, err := server.NewGRPCServer(server.GRPCConfig{
+		Host:          args.Host,
+		Port:          args.Port,
+		Server:        server.NewServer(server.Config{InstrumentUseCases: instruments, MarketUseCases: u}),
+		ShutdownDelay: args.ShutdownDelay,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start gRPC server: %w", err)
+	}
+
+	ms := monitoring.NewServer(monitoring.Config{
+		Health:        monitoring.NewHealth(monitoring.HealthConfig{Checks: checks, GRPC: gs.Health()}),
+		Port:          args.MetricsPort,
+		ShutdownDelay: args.ShutdownDelay,
+	})
+	go func() {
+		if err := ms.Run(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to run monitoring server")
+		}
+	}()
+
+	if err := gs.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("failed to run gRPC server: %w", err)
+	}
+
+	log.Info().Msg("Shut down")
+
+	return nil
+}
+
+// closeAll closes anything that holds connections or files open, e.g. the Redis client.
+func closeAll(closers ...any) {
+	for _, c := range closers {
+		if c, ok := c.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				log.Err(err).Msg("Failed to close")
+			}
+		}
+	}
+}
+
+// newStoreBroker returns the store set by --store.
+func newStoreBroker(args cliArgs) (storeBroker, error) {
+	switch args.Store {
+	case "memory":
+		return memory.NewClient(memory.Config{TimeNow: time.Now}), nil
+	case "redis":
+		return newRedisClient(args)
+	default:
+		return nil, fmt.Errorf("unknown store %s", args.Store)
+	}
+}
+
+// newHistory returns the history set by --history, or nil if market history isn't kept.
+func newHistory(args cliArgs) (usecases.History, error) {
+	switch args.History {
+	case "none":
+		return nil, nil
+	case "redis":
+		return newRedisClient(args)
+	case "file":
+		return file.NewHistory(file.HistoryConfig{Dir: args.HistoryDir})
+	default:
+		return nil, fmt.Errorf("unknown history %s", args.History)
+	}
+}
+
+// newInstruments returns the registry of order constraints, from each exchange's REST API.
+func newInstruments(args cliArgs) *usecases.Instruments {
+	httpClient := http.Client{Timeout: args.HTTPClientTimeout}
+
+	return usecases.NewInstruments(usecases.InstrumentsConfig{
+		RefreshInterval: args.InstrumentsRefresh,
+		Sources: []usecases.InstrumentSource{
+			binance.NewMetadata(binance.MetadataConfig{Hostname: args.BinanceHostname, HTTPClient: httpClient}),
+			bybit.NewMetadata(bybit.MetadataConfig{Hostname: args.BybitHostname, HTTPClient: httpClient}),
+			coinbase.NewMetadata(coinbase.MetadataConfig{Hostname: args.CoinbaseHostname, HTTPClient: httpClient}),
+			kraken.NewMetadata(kraken.MetadataConfig{Hostname: args.KrakenHostname, HTTPClient: httpClient}),
+			kucoin.NewMetadata(kucoin.MetadataConfig{Hostname: args.KuCoinHostname, HTTPClient: httpClient}),
+			okx.NewMetadata(okx.MetadataConfig{Hostname: args.OKXHostname, HTTPClient: httpClient}),
+		},
+	})
+}
+
+func newRedisClient(args cliArgs) (*redis.Client, error) {
+	if args.RedisHost == "" || args.RedisPort == "" {
+		return nil, fmt.Errorf("--redis-host and --redis-port are required for redis")
+	}
+
+	return redis.NewClient(redis.Config{Host: args.RedisHost, Port: args.RedisPort}), nil
+}
+
+// newExchange returns the websocket protocol of an exchange.
+func newExchange(args cliArgs, e entities.Exchange) (connector.Exchange, error) {
+	httpClient := http.Client{Timeout: args.HTTPClientTimeout}
+
+	switch e {
+	case entities.ExchangeBinance:
+		return binance.NewExchange(binance.ExchangeConfig{
+			APIKey:       args.BinanceAPIKey,
+			Hostname:     args.BinanceHostname,
+			HTTPClient:   httpClient,
+			WebsocketURL: args.BinanceWebsocketURL,
+		}), nil
+	case entities.ExchangeBybit:
+		return bybit.NewExchange(bybit.ExchangeConfig{WebsocketURL: args.BybitWebsocketURL}), nil
+	case entities.ExchangeCoinbase:
+		return coinbase.NewExchange(coinbase.ExchangeConfig{WebsocketURL: args.CoinbaseWebsocketURL}), nil
+	case entities.ExchangeKraken:
+		return kraken.NewExchange(kraken.ExchangeConfig{TimeNow: time.Now, WebsocketURL: args.KrakenWebsocketURL}), nil
+	case entities.ExchangeKuCoin:
+		return kucoin.NewExchange(kucoin.ExchangeConfig{
+			Hostname:   args.KuCoinHostname,
+			HTTPClient: httpClient,
+			TimeNow:    time.Now,
+		}), nil
+	case entities.ExchangeOKX:
+		return okx.NewExchange(okx.ExchangeConfig{WebsocketURL: args.OKXWebsocketURL}), nil
+	default:
+		return nil, fmt.Errorf("unknown exchange %s", e)
+	}
+}
@@ -0,0 +1,25 @@
+// Command bybitupdater keeps the Bybit markets in trading_pairs.yaml up to date in Redis.
+package main
+
+import (
+	"github.com/alexflint/go-arg"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/bybit"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/updater"
+	"github.com/rs/zerolog/log"
+)
+
+type cliArgs struct {
+	updater.Args
+	BybitWebsocketURL string `arg:"required,env:BYBIT_WEBSOCKET_URL"`
+}
+
+func main() {
+	var args cliArgs
+	arg.MustParse(&args)
+
+	if err := updater.Run(args.Args, bybit.NewExchange(bybit.ExchangeConfig{
+		WebsocketURL: args.BybitWebsocketURL,
+	})); err != nil {
+		log.Fatal().Err(err).Msg("Failed to run updater")
+	}
+}
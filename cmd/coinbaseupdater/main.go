@@ -0,0 +1,25 @@
+// Command coinbaseupdater keeps the Coinbase markets in trading_pairs.yaml up to date in Redis.
+package main
+
+import (
+	"github.com/alexflint/go-arg"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/coinbase"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/updater"
+	"github.com/rs/zerolog/log"
+)
+
+type cliArgs struct {
+	updater.Args
+	CoinbaseWebsocketURL string `arg:"required,env:COINBASE_WEBSOCKET_URL"`
+}
+
+func main() {
+	var args cliArgs
+	arg.MustParse(&args)
+
+	if err := updater.Run(args.Args, coinbase.NewExchange(coinbase.ExchangeConfig{
+		WebsocketURL: args.CoinbaseWebsocketURL,
+	})); err != nil {
+		log.Fatal().Err(err).Msg("Failed to run updater")
+	}
+}
@@ -0,0 +1,91 @@
+// Command discover finds the pairs listed on two or more exchanges and writes them to trading_pairs.yaml.
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/domain/usecases"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/binance"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/kucoin"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/pairsfile"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+type cliArgs struct {
+	BinanceHostname   string        `arg:"env:BINANCE_HOSTNAME" default:"https://api.binance.com"`
+	DryRun            bool          `arg:"--dry-run,env:DRY_RUN" help:"log the pairs found without writing them"`
+	HTTPClientTimeout time.Duration `arg:"env:HTTP_CLIENT_TIMEOUT" default:"30s"`
+	KuCoinHostname    string        `arg:"env:KUCOIN_HOSTNAME" default:"https://api.kucoin.com"`
+	LogLevel          string        `arg:"--log-level,env:LOG_LEVEL" default:"info"`
+	MinExchanges      int           `arg:"--min-exchanges,env:MIN_EXCHANGES" default:"2" help:"exchanges a pair must pass the filters on"`
+	MinVolume         float64       `arg:"--min-volume,env:MIN_VOLUME" default:"0" help:"minimum 24hr volume in the quote asset"`
+	Output            string        `arg:"--output,env:OUTPUT" default:"data/trading_pairs.yaml" help:"trading pairs file, only the discovered exchanges are rewritten"`
+	QuoteAssets       []string      `arg:"--quote-assets,env:QUOTE_ASSETS" help:"quote assets to keep, comma separated in the env var"`
+	Statuses          []string      `arg:"--status,env:STATUS" help:"trading or halted"`
+}
+
+func main() {
+	// go-arg doesn't support default tags on slices
+	args := cliArgs{
+		QuoteAssets: []string{"USDT"},
+		Statuses:    []string{string(entities.ListingStatusTrading)},
+	}
+	arg.MustParse(&args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logLevel, err := zerolog.ParseLevel(args.LogLevel)
+	if err != nil {
+		log.Warn().Msg("Failed to parse log level, defaulting to info")
+		logLevel = zerolog.InfoLevel
+	}
+	zerolog.SetGlobalLevel(logLevel)
+
+	httpClient := http.Client{Timeout: args.HTTPClientTimeout}
+
+	d := usecases.NewDiscovery(usecases.DiscoveryConfig{
+		MinExchanges: args.MinExchanges,
+		Sources: []usecases.ListingSource{
+			binance.NewMetadata(binance.MetadataConfig{Hostname: args.BinanceHostname, HTTPClient: httpClient}),
+			kucoin.NewMetadata(kucoin.MetadataConfig{Hostname: args.KuCoinHostname, HTTPClient: httpClient}),
+		},
+	})
+
+	statuses := make([]entities.ListingStatus, len(args.Statuses))
+	for i, s := range args.Statuses {
+		statuses[i] = entities.ListingStatus(s)
+	}
+
+	pairs, err := d.Discover(ctx, usecases.ListingFilter{
+		QuoteAssets:   args.QuoteAssets,
+		MinVolume24hr: args.MinVolume,
+		Statuses:      statuses,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to discover trading pairs")
+	}
+
+	for e, p := range pairs {
+		log.Info().Str("exchange", e.String()).Int("count", len(p)).Strs("pairs", p).Msg("Discovered trading pairs")
+	}
+
+	if args.DryRun {
+		return
+	}
+
+	// Running updaters watch the file, so pick the new pairs up without restarting
+	if err = pairsfile.Update(args.Output, pairs); err != nil {
+		log.Fatal().Err(err).Msg("Failed to write trading pairs")
+	}
+
+	log.Info().Str("path", args.Output).Msg("Wrote trading pairs")
+}
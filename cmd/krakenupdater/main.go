@@ -0,0 +1,28 @@
+// Command krakenupdater keeps the Kraken markets in trading_pairs.yaml up to date in Redis.
+package main
+
+import (
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/kraken"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/updater"
+	"github.com/rs/zerolog/log"
+)
+
+type cliArgs struct {
+	updater.Args
+	KrakenWebsocketURL string `arg:"required,env:KRAKEN_WEBSOCKET_URL"`
+}
+
+func main() {
+	var args cliArgs
+	arg.MustParse(&args)
+
+	if err := updater.Run(args.Args, kraken.NewExchange(kraken.ExchangeConfig{
+		TimeNow:      time.Now,
+		WebsocketURL: args.KrakenWebsocketURL,
+	})); err != nil {
+		log.Fatal().Err(err).Msg("Failed to run updater")
+	}
+}
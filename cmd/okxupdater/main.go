@@ -0,0 +1,25 @@
+// Command okxupdater keeps the OKX markets in trading_pairs.yaml up to date in Redis.
+package main
+
+import (
+	"github.com/alexflint/go-arg"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/okx"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/updater"
+	"github.com/rs/zerolog/log"
+)
+
+type cliArgs struct {
+	updater.Args
+	OKXWebsocketURL string `arg:"required,env:OKX_WEBSOCKET_URL"`
+}
+
+func main() {
+	var args cliArgs
+	arg.MustParse(&args)
+
+	if err := updater.Run(args.Args, okx.NewExchange(okx.ExchangeConfig{
+		WebsocketURL: args.OKXWebsocketURL,
+	})); err != nil {
+		log.Fatal().Err(err).Msg("Failed to run updater")
+	}
+}
@@ -2,36 +2,58 @@ package main
 
 import (
 	"context"
-	"fmt"
-	"net"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/alexflint/go-arg"
 	"github.com/peterstirrup/arbenheimer/internal/domain/usecases"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/binance"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/bybit"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/coinbase"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/feesfile"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/kraken"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/kucoin"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/monitoring"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/okx"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/pairsfile"
 	"github.com/peterstirrup/arbenheimer/internal/inbound/server"
-	"github.com/peterstirrup/arbenheimer/internal/inbound/server/pb"
 	"github.com/peterstirrup/arbenheimer/internal/outbound/redis"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/health"
-	"google.golang.org/grpc/health/grpc_health_v1"
-	"google.golang.org/grpc/keepalive"
 )
 
 const (
-	maxConnectionIdle     = 10 * time.Minute
-	maxConnectionAge      = 5 * time.Minute
-	maxConnectionAgeGrace = 5 * time.Minute
-	defaultTime           = 5 * time.Minute
+	// feesFile sets the fee tier of each exchange.
+	feesFile = "data/fees.yaml"
+
+	// tradingPairsFile lists the trading pairs of each exchange. It's watched, so the pairs used when a request
+	// doesn't list any can be changed while running.
+	tradingPairsFile = "data/trading_pairs.yaml"
 )
 
 type cliArgs struct {
-	Host      string `arg:"--host,required,env:HOST"`
-	LogLevel  string `arg:"--log-level,env:LOG_LEVEL" default:"debug"`
-	Port      int    `arg:"env:PORT" default:"9000"`
-	RedisHost string `arg:"--redis-host,required,env:REDIS_HOST"`
-	RedisPort string `arg:"--redis-port,required,env:REDIS_PORT"`
+	BinanceHostname    string        `arg:"env:BINANCE_HOSTNAME" default:"https://api.binance.com"`
+	BybitHostname      string        `arg:"env:BYBIT_HOSTNAME" default:"https://api.bybit.com"`
+	CoinbaseHostname   string        `arg:"env:COINBASE_HOSTNAME" default:"https://api.exchange.coinbase.com"`
+	Host               string        `arg:"--host,required,env:HOST"`
+	HTTPClientTimeout  time.Duration `arg:"env:HTTP_CLIENT_TIMEOUT" default:"10s"`
+	InstrumentsRefresh time.Duration `arg:"--instruments-refresh,env:INSTRUMENTS_REFRESH" default:"1h" help:"how often order constraints are fetched from the exchanges"`
+	KrakenHostname     string        `arg:"env:KRAKEN_HOSTNAME" default:"https://api.kraken.com"`
+	KuCoinHostname     string        `arg:"env:KUCOIN_HOSTNAME" default:"https://api.kucoin.com"`
+	LogLevel           string        `arg:"--log-level,env:LOG_LEVEL" default:"debug"`
+	MetricsPort        int           `arg:"--metrics-port,env:METRICS_PORT" default:"9090"`
+	OKXHostname        string        `arg:"env:OKX_HOSTNAME" default:"https://www.okx.com"`
+	Port               int           `arg:"env:PORT" default:"9000"`
+	RedisHost          string        `arg:"--redis-host,required,env:REDIS_HOST"`
+	RedisPort          string        `arg:"--redis-port,required,env:REDIS_PORT"`
+	ShutdownDelay      time.Duration `arg:"--shutdown-delay,env:SHUTDOWN_DELAY" default:"5s" help:"how long health checks report not serving before the server stops, so load balancers stop sending it requests"`
+	StaleAfter         time.Duration `arg:"--stale-after,env:STALE_AFTER" default:"1m" help:"age after which a quote is stale"`
+	Tracing            string        `arg:"--tracing,env:TRACING" default:"none" help:"none, stdout or otlp"`
+	TracingSampleRatio float64       `arg:"--tracing-sample-ratio,env:TRACING_SAMPLE_RATIO" default:"1" help:"fraction of traces recorded"`
 }
 
 func main() {
@@ -45,98 +67,109 @@ func main() {
 	}
 	zerolog.SetGlobalLevel(logLevel)
 
-	ctx := context.Background()
-
-	rc := redis.NewClient(redis.Config{Host: args.RedisHost, Port: args.RedisPort})
+	// Cancelled on SIGINT or SIGTERM, e.g. when a deploy stops the container
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	u := usecases.NewMarket(usecases.MarketConfig{
-		Store:   rc,
-		TimeNow: time.Now,
+	shutdownTracing, err := monitoring.SetupTracing(ctx, monitoring.TracingConfig{
+		Exporter:    args.Tracing,
+		SampleRatio: args.TracingSampleRatio,
+		ServiceName: "server",
 	})
-
-	s := server.NewServer(server.Config{MarketUseCases: u})
-	gs, err := newGRPCServer(ctx, args.Host, args.Port)
-
-	pb.RegisterArbenheimerServiceServer(gs.s, s)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to start gRPC server")
+		log.Fatal().Err(err).Msg("Failed to set up tracing")
 	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error().Err(err).Msg("Failed to shut down tracing")
+		}
+	}()
 
-	if err := gs.Run(ctx); err != nil {
-		log.Fatal().Err(err).Msg("Failed to run gRPC server")
+	exchanges, err := pairsfile.Exchanges(tradingPairsFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get exchanges")
 	}
-}
 
-// gRPCServer type wraps the base grpc.Server type and simplifies serving
-// over TCP connections. The Run method provides context cancellation handling
-// not provided by the base type.
-type gRPCServer struct {
-	s       *grpc.Server
-	lis     net.Listener
-	hs      *health.Server
-	address string
-}
-
-// newGRPCServer returns a new gRPC server.
-func newGRPCServer(ctx context.Context, host string, port int, opts ...grpc.ServerOption) (*gRPCServer, error) {
-	address := fmt.Sprintf("%s:%d", host, port)
+	pairs, err := pairsfile.Load(tradingPairsFile, "")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to get trading pairs")
+	}
 
-	lis, err := net.Listen("tcp", address)
+	fees, err := feesfile.Load(feesFile, exchanges)
 	if err != nil {
-		return nil, fmt.Errorf("%w while starting tcp listener", err)
+		log.Fatal().Err(err).Msg("Failed to get fee schedule")
 	}
-	log.Debug().Msg("tcp listener started")
 
-	c := defaultGRPCConfig()
-	c.serverOptions = append(c.serverOptions, opts...)
+	rc := redis.NewClient(redis.Config{Host: args.RedisHost, Port: args.RedisPort})
 
-	s := grpc.NewServer(c.serverOptions...)
-	healthServer := health.NewServer()
-	grpc_health_v1.RegisterHealthServer(s, healthServer)
+	httpClient := http.Client{Timeout: args.HTTPClientTimeout}
+	instruments := usecases.NewInstruments(usecases.InstrumentsConfig{
+		RefreshInterval: args.InstrumentsRefresh,
+		Sources: []usecases.InstrumentSource{
+			binance.NewMetadata(binance.MetadataConfig{Hostname: args.BinanceHostname, HTTPClient: httpClient}),
+			bybit.NewMetadata(bybit.MetadataConfig{Hostname: args.BybitHostname, HTTPClient: httpClient}),
+			coinbase.NewMetadata(coinbase.MetadataConfig{Hostname: args.CoinbaseHostname, HTTPClient: httpClient}),
+			kraken.NewMetadata(kraken.MetadataConfig{Hostname: args.KrakenHostname, HTTPClient: httpClient}),
+			kucoin.NewMetadata(kucoin.MetadataConfig{Hostname: args.KuCoinHostname, HTTPClient: httpClient}),
+			okx.NewMetadata(okx.MetadataConfig{Hostname: args.OKXHostname, HTTPClient: httpClient}),
+		},
+	})
+	go func() {
+		if err := instruments.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Err(err).Msg("Failed to run instruments")
+		}
+	}()
 
-	return &gRPCServer{s: s, lis: lis, address: address, hs: healthServer}, nil
-}
+	u := usecases.NewMarket(usecases.MarketConfig{
+		Broker:       rc,
+		Candles:      rc,
+		Fees:         fees,
+		History:      rc,
+		Instruments:  instruments,
+		StaleAfter:   args.StaleAfter,
+		Store:        rc,
+		TimeNow:      time.Now,
+		TradingPairs: pairs,
+	})
 
-type grpcConfig struct {
-	serverOptions []grpc.ServerOption
-}
+	mw := pairsfile.NewWatcher(pairsfile.WatcherConfig{Path: tradingPairsFile, Updater: u})
+	go func() {
+		if err := mw.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Err(err).Msg("Failed to watch trading pairs")
+		}
+	}()
 
-func defaultGRPCConfig() grpcConfig {
-	return grpcConfig{
-		serverOptions: []grpc.ServerOption{
-			grpc.KeepaliveParams(keepalive.ServerParameters{
-				MaxConnectionIdle:     maxConnectionIdle,
-				MaxConnectionAge:      maxConnectionAge,
-				MaxConnectionAgeGrace: maxConnectionAgeGrace,
-				Time:                  defaultTime,
-			}),
-		},
+	gs, err := server.NewGRPCServer(server.GRPCConfig{
+		Host:          args.Host,
+		Port:          args.Port,
+		Server:        server.NewServer(server.Config{InstrumentUseCases: instruments, MarketUseCases: u}),
+		ShutdownDelay: args.ShutdownDelay,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to start gRPC server")
 	}
-}
-
-// Run starts the gRPC server and blocks until the context is cancelled.
-func (s *gRPCServer) Run(ctx context.Context) error {
-	log.Info().Msg("starting gRPC server")
 
-	done := make(chan struct{})
+	checks := []monitoring.HealthCheck{{Name: "redis", Kind: monitoring.Readiness, Check: rc.Ping}}
+	checks = append(checks, monitoring.FreshnessChecks(exchanges, u.CheckFreshness)...)
 
+	ms := monitoring.NewServer(monitoring.Config{
+		Health:        monitoring.NewHealth(monitoring.HealthConfig{Checks: checks, GRPC: gs.Health()}),
+		Port:          args.MetricsPort,
+		ShutdownDelay: args.ShutdownDelay,
+	})
 	go func() {
-		select {
-		case <-ctx.Done():
-			s.hs.SetServingStatus("ready", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
-			s.s.GracefulStop()
-			<-done
-
-		case <-done:
+		if err := ms.Run(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to run monitoring server")
 		}
 	}()
 
-	err := s.s.Serve(s.lis)
-	done <- struct{}{}
+	if err := gs.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		log.Fatal().Err(err).Msg("Failed to run gRPC server")
+	}
 
-	if err != nil {
-		return err
+	if err := rc.Close(); err != nil {
+		log.Err(err).Msg("Failed to close Redis client")
 	}
 
-	return ctx.Err()
+	log.Info().Msg("Shut down")
 }
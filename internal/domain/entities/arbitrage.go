@@ -0,0 +1,19 @@
+package entities
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Arbitrage is the largest quantity of a trading pair that can be bought on one exchange and sold on another
+// while every unit traded remains profitable after fees.
+type Arbitrage struct {
+	TradingPair  string          // e.g. "BTC/USDT"
+	BuyExchange  Exchange        // Exchange whose asks are bought
+	SellExchange Exchange        // Exchange whose bids are sold to
+	Quantity     decimal.Decimal // Base asset quantity
+	BuyVWAP      decimal.Decimal // Volume weighted average buy price
+	SellVWAP     decimal.Decimal // Volume weighted average sell price
+	BuyNotional  decimal.Decimal // Quote asset spent buying, before fees
+	SellNotional decimal.Decimal // Quote asset received selling, before fees
+	Profit       decimal.Decimal // Expected profit in the quote asset, after taker fees on both exchanges
+}
@@ -0,0 +1,108 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CandleInterval is the period covered by a candle, e.g. "1m".
+type CandleInterval string
+
+const (
+	CandleInterval1s CandleInterval = "1s"
+	CandleInterval1m CandleInterval = "1m"
+	CandleInterval5m CandleInterval = "5m"
+	CandleInterval1h CandleInterval = "1h"
+)
+
+var CandleIntervals = []CandleInterval{
+	CandleInterval1s,
+	CandleInterval1m,
+	CandleInterval5m,
+	CandleInterval1h,
+}
+
+// Duration returns the length of the interval, or zero if it isn't one of CandleIntervals.
+func (i CandleInterval) Duration() time.Duration {
+	switch i {
+	case CandleInterval1s:
+		return time.Second
+	case CandleInterval1m:
+		return time.Minute
+	case CandleInterval5m:
+		return 5 * time.Minute
+	case CandleInterval1h:
+		return time.Hour
+	default:
+		return 0
+	}
+}
+
+func (i CandleInterval) String() string {
+	return string(i)
+}
+
+// Candle is an OHLCV bar of the last traded price of a trading pair on an exchange.
+type Candle struct {
+	TradingPair string // e.g. "BTC/USDT"
+	Exchange    Exchange
+	Interval    CandleInterval
+	OpenTime    time.Time // Start of the interval
+	Open        decimal.Decimal
+	High        decimal.Decimal
+	Low         decimal.Decimal
+	Close       decimal.Decimal
+	Volume      float64   // Quote asset traded during the interval, the sum of its trades
+	LastTradeID int64     // ID of the latest trade counted in Volume, zero if none had an ID
+	FirstUpdate time.Time // Time of the update that set Open
+	LastUpdate  time.Time // Time of the update that set Close
+}
+
+// NewCandle returns a candle for the interval containing timestamp, of a single update at price with no volume.
+func NewCandle(tradingPair string, exchange Exchange, interval CandleInterval, timestamp time.Time, price decimal.Decimal) Candle {
+	return Candle{
+		TradingPair: tradingPair,
+		Exchange:    exchange,
+		Interval:    interval,
+		OpenTime:    timestamp.Truncate(interval.Duration()),
+		Open:        price,
+		High:        price,
+		Low:         price,
+		Close:       price,
+		FirstUpdate: timestamp,
+		LastUpdate:  timestamp,
+	}
+}
+
+// Contains reports whether timestamp falls within the candle's interval.
+func (c Candle) Contains(timestamp time.Time) bool {
+	return !timestamp.Before(c.OpenTime) && timestamp.Before(c.OpenTime.Add(c.Interval.Duration()))
+}
+
+// Merge adds the updates in another candle for the same interval to the candle, in any order. The earliest update
+// opens the candle and the latest closes it, so candles built from different updates, e.g. by redundant updaters,
+// combine into the same candle. Each updater sends a market's trades in order, so volume from a trade ID the candle
+// has already counted is a redundant updater's copy of it, and is skipped.
+func (c *Candle) Merge(o Candle) {
+	if o.FirstUpdate.Before(c.FirstUpdate) {
+		c.Open = o.Open
+		c.FirstUpdate = o.FirstUpdate
+	}
+	if !o.LastUpdate.Before(c.LastUpdate) {
+		c.Close = o.Close
+		c.LastUpdate = o.LastUpdate
+	}
+
+	if o.High.GreaterThan(c.High) {
+		c.High = o.High
+	}
+	if o.Low.LessThan(c.Low) {
+		c.Low = o.Low
+	}
+
+	if o.LastTradeID == 0 || o.LastTradeID > c.LastTradeID {
+		c.Volume += o.Volume
+		c.LastTradeID = max(c.LastTradeID, o.LastTradeID)
+	}
+}
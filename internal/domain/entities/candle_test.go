@@ -0,0 +1,70 @@
+package entities_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCandle_Merge(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	update := func(offset time.Duration, price float64, volume float64) entities.Candle {
+		c := entities.NewCandle("BTC/USDT", entities.ExchangeBinance, entities.CandleInterval1m, start.Add(offset), decimal.NewFromFloat(price))
+		c.Volume = volume
+		return c
+	}
+
+	t.Run("combines updates in any order", func(t *testing.T) {
+		updates := []entities.Candle{
+			update(10*time.Second, 100, 1),
+			update(40*time.Second, 98, 2),
+			update(20*time.Second, 103, 3),
+			update(5*time.Second, 101, 4),
+		}
+
+		c := updates[0]
+		for _, u := range updates[1:] {
+			c.Merge(u)
+		}
+
+		require.Equal(t, start, c.OpenTime)
+		require.Equal(t, "101", c.Open.String())
+		require.Equal(t, "103", c.High.String())
+		require.Equal(t, "98", c.Low.String())
+		require.Equal(t, "98", c.Close.String())
+		require.Equal(t, float64(10), c.Volume)
+		require.Equal(t, start.Add(5*time.Second), c.FirstUpdate)
+		require.Equal(t, start.Add(40*time.Second), c.LastUpdate)
+	})
+
+	t.Run("closes at the later of updates at the same time", func(t *testing.T) {
+		c := update(10*time.Second, 100, 0)
+		c.Merge(update(10*time.Second, 99, 0))
+
+		require.Equal(t, "100", c.Open.String())
+		require.Equal(t, "99", c.Close.String())
+	})
+
+	t.Run("counts each trade once", func(t *testing.T) {
+		trade := func(offset time.Duration, id int64) entities.Candle {
+			c := update(offset, 100, 2)
+			c.LastTradeID = id
+			return c
+		}
+
+		// Two updaters send trades 1 and 2, the second after the first
+		c := trade(0, 1)
+		c.Merge(trade(time.Second, 2))
+		c.Merge(trade(0, 1))
+		c.Merge(trade(time.Second, 2))
+		c.Merge(update(2*time.Second, 101, 0))
+
+		require.Equal(t, float64(4), c.Volume)
+		require.Equal(t, int64(2), c.LastTradeID)
+		require.Equal(t, "101", c.Close.String())
+	})
+}
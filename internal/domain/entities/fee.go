@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// Fee holds the fee rates charged by an exchange, e.g. 0.001 is 0.1%.
+type Fee struct {
+	Maker decimal.Decimal
+	Taker decimal.Decimal
+}
+
+// ExchangeFees holds the fees for each VIP tier on an exchange, and the tier we trade at.
+type ExchangeFees struct {
+	Tier  int
+	Tiers map[int]Fee
+}
+
+// FeeSchedule holds the fees for each exchange. Exchanges not in the schedule are treated as fee free, so the schedule
+// loaded from fees.yaml must cover every exchange traded.
+type FeeSchedule map[Exchange]ExchangeFees
+
+// Fee returns the fee rates for the tier we trade at on the given exchange.
+func (s FeeSchedule) Fee(e Exchange) Fee {
+	fees, ok := s[e]
+	if !ok {
+		return Fee{}
+	}
+
+	return fees.Tiers[fees.Tier]
+}
+
+// NetProfit returns the profit per unit from buying at buyPrice on one exchange and selling at sellPrice on another,
+// after paying taker fees on both sides.
+func (s FeeSchedule) NetProfit(buy Exchange, buyPrice decimal.Decimal, sell Exchange, sellPrice decimal.Decimal) decimal.Decimal {
+	cost := buyPrice.Mul(decimal.NewFromInt(1).Add(s.Fee(buy).Taker))
+	proceeds := sellPrice.Mul(decimal.NewFromInt(1).Sub(s.Fee(sell).Taker))
+
+	return proceeds.Sub(cost)
+}
@@ -0,0 +1,80 @@
+package entities
+
+import (
+	"math/big"
+
+	"github.com/shopspring/decimal"
+)
+
+// Instrument holds the order constraints of a trading pair on an exchange. Prices and quantities have to be rounded
+// to them before an order can be placed. Zero constraints aren't enforced.
+type Instrument struct {
+	Exchange    Exchange
+	TradingPair string // e.g. "BTC/USDT"
+	Status      ListingStatus
+	TickSize    decimal.Decimal // Price increment, in the quote asset
+	StepSize    decimal.Decimal // Quantity increment, in the base asset
+	MinQuantity decimal.Decimal // In the base asset
+	MaxQuantity decimal.Decimal // In the base asset
+	MinNotional decimal.Decimal // Price * quantity, in the quote asset
+}
+
+// RoundPrice rounds a price down to the tick size.
+func (i Instrument) RoundPrice(price decimal.Decimal) decimal.Decimal {
+	return roundDown(price, i.TickSize)
+}
+
+// RoundQuantity rounds a quantity down to the step size.
+func (i Instrument) RoundQuantity(quantity decimal.Decimal) decimal.Decimal {
+	return roundDown(quantity, i.StepSize)
+}
+
+// RoundQuantityForBoth rounds a quantity down to a multiple of both instruments' step sizes, so the same quantity
+// can be ordered on both exchanges, e.g. steps of 0.25 and 0.1 round down to a multiple of 0.5.
+func RoundQuantityForBoth(a, b Instrument, quantity decimal.Decimal) decimal.Decimal {
+	return roundDown(quantity, lcm(a.StepSize, b.StepSize))
+}
+
+// Allows returns whether an order of quantity at price is within the instrument's limits. The price and quantity
+// should already be rounded.
+func (i Instrument) Allows(price, quantity decimal.Decimal) bool {
+	if i.Status != ListingStatusTrading {
+		return false
+	}
+
+	if quantity.LessThan(i.MinQuantity) {
+		return false
+	}
+
+	if !i.MaxQuantity.IsZero() && quantity.GreaterThan(i.MaxQuantity) {
+		return false
+	}
+
+	return !price.Mul(quantity).LessThan(i.MinNotional)
+}
+
+// roundDown rounds d down to a multiple of increment, or returns it as it is if increment is zero.
+func roundDown(d, increment decimal.Decimal) decimal.Decimal {
+	if !increment.IsPositive() {
+		return d
+	}
+
+	return d.Div(increment).Floor().Mul(increment)
+}
+
+// lcm returns the least common multiple of two increments, or the other if either is zero, as it isn't enforced.
+func lcm(a, b decimal.Decimal) decimal.Decimal {
+	if !a.IsPositive() {
+		return b
+	}
+	if !b.IsPositive() {
+		return a
+	}
+
+	// Scaled to whole numbers, e.g. 0.25 and 0.1 --> 25 and 10
+	exp := min(a.Exponent(), b.Exponent())
+	x, y := a.Shift(-exp).BigInt(), b.Shift(-exp).BigInt()
+
+	gcd := new(big.Int).GCD(nil, nil, x, y)
+	return decimal.NewFromBigInt(new(big.Int).Mul(new(big.Int).Quo(x, gcd), y), exp)
+}
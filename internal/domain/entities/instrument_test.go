@@ -0,0 +1,50 @@
+package entities_test
+
+import (
+	"testing"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrument(t *testing.T) {
+	d := decimal.RequireFromString
+
+	i := entities.Instrument{
+		Status:      entities.ListingStatusTrading,
+		TickSize:    d("0.01"),
+		StepSize:    d("0.00001"),
+		MinQuantity: d("0.00001"),
+		MaxQuantity: d("9000"),
+		MinNotional: d("5"),
+	}
+
+	t.Run("rounds down to the tick and step sizes", func(t *testing.T) {
+		require.Equal(t, "69000.12", i.RoundPrice(d("69000.129")).String())
+		require.Equal(t, "0.12345", i.RoundQuantity(d("0.123456789")).String())
+		require.Equal(t, "1.5", entities.Instrument{}.RoundQuantity(d("1.5")).String())
+	})
+
+	t.Run("rounds down to a multiple of both step sizes", func(t *testing.T) {
+		quarter := entities.Instrument{StepSize: d("0.25")}
+		tenth := entities.Instrument{StepSize: d("0.1")}
+
+		// Nesting the roundings would give 1.2, which isn't a multiple of 0.25
+		require.Equal(t, "1", entities.RoundQuantityForBoth(quarter, tenth, d("1.27")).String())
+		require.Equal(t, "1.5", entities.RoundQuantityForBoth(tenth, quarter, d("1.99")).String())
+		require.Equal(t, "0.001", entities.RoundQuantityForBoth(i, entities.Instrument{StepSize: d("0.001")}, d("0.0019")).String())
+		require.Equal(t, "1.27", entities.RoundQuantityForBoth(entities.Instrument{}, entities.Instrument{}, d("1.27")).String())
+	})
+
+	t.Run("allows orders within the limits", func(t *testing.T) {
+		require.True(t, i.Allows(d("69000"), d("0.001")))
+		require.False(t, i.Allows(d("69000"), d("0.00005")), "under min notional")
+		require.False(t, i.Allows(d("1"), d("9001")), "over max quantity")
+		require.False(t, i.Allows(d("69000"), d("0")), "under min quantity")
+
+		halted := i
+		halted.Status = entities.ListingStatusHalted
+		require.False(t, halted.Allows(d("69000"), d("0.001")))
+	})
+}
@@ -0,0 +1,19 @@
+package entities
+
+// Listing is a trading pair listed on an exchange.
+type Listing struct {
+	Exchange    Exchange
+	TradingPair string // e.g. "BTC/USDT"
+	Base        string // e.g. "BTC"
+	Quote       string // e.g. "USDT"
+	Status      ListingStatus
+	Volume24hr  float64 // In the quote asset, zero if the exchange didn't report it
+}
+
+// ListingStatus is whether a listing can be traded, normalized across exchanges.
+type ListingStatus string
+
+const (
+	ListingStatusTrading ListingStatus = "trading"
+	ListingStatusHalted  ListingStatus = "halted" // Listed, but trading is paused, delisted or not started yet
+)
@@ -13,19 +13,35 @@ type Market struct {
 	BestSellPrice   decimal.Decimal
 	LastTradedPrice decimal.Decimal
 	Timestamp       time.Time // Timestamp of the market data
-	Volume24hr      float64
+	Volume24hr      float64   // Quote asset traded in the last 24 hours, a rolling total reported by the exchange
+}
+
+// Quote is a market with how long ago it was last updated, so a feed that's stopped updating can be told apart from
+// a live one before its market expires from the store.
+type Quote struct {
+	Market
+	Age   time.Duration // Time since the market's timestamp
+	Stale bool          // Whether Age is over the staleness threshold
 }
 
 type Exchange string
 
 const (
-	ExchangeBinance Exchange = "binance"
-	ExchangeKuCoin  Exchange = "kucoin"
+	ExchangeBinance  Exchange = "binance"
+	ExchangeBybit    Exchange = "bybit"
+	ExchangeCoinbase Exchange = "coinbase"
+	ExchangeKraken   Exchange = "kraken"
+	ExchangeKuCoin   Exchange = "kucoin"
+	ExchangeOKX      Exchange = "okx"
 )
 
 var Exchanges = []Exchange{
 	ExchangeBinance,
 	ExchangeKuCoin,
+	ExchangeCoinbase,
+	ExchangeKraken,
+	ExchangeOKX,
+	ExchangeBybit,
 }
 
 func (e Exchange) String() string {
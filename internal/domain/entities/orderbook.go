@@ -0,0 +1,63 @@
+package entities
+
+import (
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// OrderBookLevel is the total quantity offered at a price.
+type OrderBookLevel struct {
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+}
+
+// OrderBook holds the level-2 depth for a trading pair on an exchange.
+type OrderBook struct {
+	TradingPair string // e.g. "BTC/USDT"
+	Exchange    Exchange
+	Bids        []OrderBookLevel // Buy orders, highest price first
+	Asks        []OrderBookLevel // Sell orders, lowest price first
+	Sequence    int64            // Exchange sequence/update ID of the last change applied
+	Timestamp   time.Time        // Timestamp of the last change applied
+}
+
+// UpdateBid sets the quantity of the bid at the given price. A zero quantity removes the level.
+func (b *OrderBook) UpdateBid(price, quantity decimal.Decimal) {
+	b.Bids = updateLevels(b.Bids, price, quantity, func(p decimal.Decimal) bool { return p.LessThanOrEqual(price) })
+}
+
+// UpdateAsk sets the quantity of the ask at the given price. A zero quantity removes the level.
+func (b *OrderBook) UpdateAsk(price, quantity decimal.Decimal) {
+	b.Asks = updateLevels(b.Asks, price, quantity, func(p decimal.Decimal) bool { return p.GreaterThanOrEqual(price) })
+}
+
+// Truncate returns a copy of the order book with at most depth levels on each side.
+func (b *OrderBook) Truncate(depth int) OrderBook {
+	c := *b
+	c.Bids = append([]OrderBookLevel(nil), b.Bids[:min(depth, len(b.Bids))]...)
+	c.Asks = append([]OrderBookLevel(nil), b.Asks[:min(depth, len(b.Asks))]...)
+
+	return c
+}
+
+// updateLevels sets the quantity at price in levels, which are sorted so that atOrAfter is false
+// for all levels before price and true from price onwards.
+func updateLevels(levels []OrderBookLevel, price, quantity decimal.Decimal, atOrAfter func(p decimal.Decimal) bool) []OrderBookLevel {
+	i := sort.Search(len(levels), func(i int) bool { return atOrAfter(levels[i].Price) })
+	exists := i < len(levels) && levels[i].Price.Equal(price)
+
+	switch {
+	case quantity.IsZero() && exists:
+		return slices.Delete(levels, i, i+1)
+	case quantity.IsZero():
+		return levels
+	case exists:
+		levels[i].Quantity = quantity
+		return levels
+	default:
+		return slices.Insert(levels, i, OrderBookLevel{Price: price, Quantity: quantity})
+	}
+}
@@ -0,0 +1,58 @@
+package entities_test
+
+import (
+	"testing"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func level(price, quantity float64) entities.OrderBookLevel {
+	return entities.OrderBookLevel{Price: decimal.NewFromFloat(price), Quantity: decimal.NewFromFloat(quantity)}
+}
+
+func TestOrderBook_Update(t *testing.T) {
+	t.Run("keeps bids highest first", func(t *testing.T) {
+		ob := entities.OrderBook{}
+
+		ob.UpdateBid(decimal.NewFromFloat(100), decimal.NewFromFloat(1))
+		ob.UpdateBid(decimal.NewFromFloat(102), decimal.NewFromFloat(2))
+		ob.UpdateBid(decimal.NewFromFloat(101), decimal.NewFromFloat(3))
+
+		require.Equal(t, []entities.OrderBookLevel{level(102, 2), level(101, 3), level(100, 1)}, ob.Bids)
+	})
+
+	t.Run("keeps asks lowest first", func(t *testing.T) {
+		ob := entities.OrderBook{}
+
+		ob.UpdateAsk(decimal.NewFromFloat(102), decimal.NewFromFloat(2))
+		ob.UpdateAsk(decimal.NewFromFloat(100), decimal.NewFromFloat(1))
+		ob.UpdateAsk(decimal.NewFromFloat(101), decimal.NewFromFloat(3))
+
+		require.Equal(t, []entities.OrderBookLevel{level(100, 1), level(101, 3), level(102, 2)}, ob.Asks)
+	})
+
+	t.Run("replaces and removes levels", func(t *testing.T) {
+		ob := entities.OrderBook{Asks: []entities.OrderBookLevel{level(100, 1), level(101, 3), level(102, 2)}}
+
+		ob.UpdateAsk(decimal.NewFromFloat(101), decimal.NewFromFloat(5))
+		ob.UpdateAsk(decimal.NewFromFloat(100), decimal.Zero)
+		ob.UpdateAsk(decimal.NewFromFloat(99), decimal.Zero)
+
+		require.Equal(t, []entities.OrderBookLevel{level(101, 5), level(102, 2)}, ob.Asks)
+	})
+
+	t.Run("truncates to depth", func(t *testing.T) {
+		ob := entities.OrderBook{
+			Bids: []entities.OrderBookLevel{level(99, 1), level(98, 1)},
+			Asks: []entities.OrderBookLevel{level(100, 1), level(101, 3), level(102, 2)},
+		}
+
+		truncated := ob.Truncate(2)
+
+		require.Len(t, truncated.Bids, 2)
+		require.Equal(t, []entities.OrderBookLevel{level(100, 1), level(101, 3)}, truncated.Asks)
+		require.Len(t, ob.Asks, 3)
+	})
+}
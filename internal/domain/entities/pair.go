@@ -0,0 +1,85 @@
+package entities
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// assetPattern matches an upper case asset code, e.g. BTC or 1INCH.
+var assetPattern = regexp.MustCompile(`^[A-Z0-9]{1,20}$`)
+
+// assetAliases maps renamed and alternative asset codes to the code used everywhere else, so the same asset has the
+// same trading pairs on every exchange.
+var assetAliases = map[string]Asset{
+	"BCHABC": "BCH",
+	"BCHSV":  "BSV",
+	"XBT":    "BTC",
+	"XDG":    "DOGE",
+}
+
+// Asset is a canonical asset code, e.g. BTC.
+type Asset string
+
+func (a Asset) String() string {
+	return string(a)
+}
+
+// NormalizeAsset returns the canonical asset for an asset code, e.g. XBT --> BTC.
+// Codes must be upper case letters and digits.
+func NormalizeAsset(code string) (Asset, error) {
+	if !assetPattern.MatchString(code) {
+		return "", fmt.Errorf("invalid asset %q", code)
+	}
+
+	if asset, ok := assetAliases[code]; ok {
+		return asset, nil
+	}
+
+	return Asset(code), nil
+}
+
+// Pair is a canonical trading pair. Its String, e.g. "BTC/USDT", is what's used as the TradingPair of markets, so
+// they can be compared across exchanges.
+type Pair struct {
+	Base  Asset
+	Quote Asset
+}
+
+// NewPair returns the canonical pair for a base and quote asset code, e.g. XBT, USD --> BTC/USD.
+func NewPair(base, quote string) (Pair, error) {
+	b, err := NormalizeAsset(base)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	q, err := NormalizeAsset(quote)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	if b == q {
+		return Pair{}, fmt.Errorf("base and quote are both %s", b)
+	}
+
+	return Pair{Base: b, Quote: q}, nil
+}
+
+// ParsePair returns the canonical pair for a BASE/QUOTE trading pair, e.g. BTC/USDT.
+func ParsePair(s string) (Pair, error) {
+	base, quote, ok := strings.Cut(s, "/")
+	if !ok {
+		return Pair{}, fmt.Errorf("invalid pair %s", s)
+	}
+
+	p, err := NewPair(base, quote)
+	if err != nil {
+		return Pair{}, fmt.Errorf("invalid pair %s: %w", s, err)
+	}
+
+	return p, nil
+}
+
+func (p Pair) String() string {
+	return string(p.Base) + "/" + string(p.Quote)
+}
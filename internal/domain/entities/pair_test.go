@@ -0,0 +1,74 @@
+package entities_test
+
+import (
+	"testing"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePair(t *testing.T) {
+	tests := map[string]string{
+		"BTC/USDT":    "BTC/USDT",
+		"XBT/USD":     "BTC/USD",
+		"BCHABC/USDT": "BCH/USDT",
+		"1INCH/USDT":  "1INCH/USDT",
+	}
+
+	for s, want := range tests {
+		t.Run(s, func(t *testing.T) {
+			p, err := entities.ParsePair(s)
+			require.NoError(t, err)
+			require.Equal(t, want, p.String())
+		})
+	}
+
+	t.Run("rejects invalid pairs", func(t *testing.T) {
+		for _, s := range []string{"BTCUSDT", "btc/usdt", "BTC-USDT", "BTC/USDT/EUR", "/USDT", "BTC/XBT"} {
+			_, err := entities.ParsePair(s)
+			require.Error(t, err, s)
+		}
+	})
+}
+
+func TestExchange_DecodeSymbol(t *testing.T) {
+	tests := []struct {
+		exchange entities.Exchange
+		symbol   string
+		want     string
+	}{
+		{entities.ExchangeKuCoin, "BTC-USDT", "BTC/USDT"},
+		{entities.ExchangeKuCoin, "BCHABC-USDT", "BCH/USDT"},
+		{entities.ExchangeKraken, "BTC/USD", "BTC/USD"},
+		{entities.ExchangeKraken, "XBT/USD", "BTC/USD"},
+		{entities.ExchangeKraken, "XDG/EUR", "DOGE/EUR"},
+		{entities.ExchangeKraken, "XXBT/ZUSD", "BTC/USD"},
+		{entities.ExchangeKraken, "XETH/ZEUR", "ETH/EUR"},
+		{entities.ExchangeKraken, "xtz/usd", "XTZ/USD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.exchange.String()+" "+tt.symbol, func(t *testing.T) {
+			p, err := tt.exchange.DecodeSymbol(tt.symbol)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, p.String())
+		})
+	}
+
+	t.Run("fails without a separator", func(t *testing.T) {
+		_, err := entities.ExchangeKraken.DecodeSymbol("XBTUSD")
+		require.Error(t, err)
+
+		_, err = entities.ExchangeBinance.DecodeSymbol("BTCUSDT")
+		require.EqualError(t, err, "binance symbols have no separator, can't decode BTCUSDT")
+	})
+}
+
+func TestExchange_EncodeSymbol(t *testing.T) {
+	p, err := entities.ParsePair("XBT/USDT")
+	require.NoError(t, err)
+
+	require.Equal(t, "BTCUSDT", entities.ExchangeBinance.EncodeSymbol(p))
+	require.Equal(t, "BTC-USDT", entities.ExchangeKuCoin.EncodeSymbol(p))
+	require.Equal(t, "BTC/USDT", entities.ExchangeKraken.EncodeSymbol(p))
+}
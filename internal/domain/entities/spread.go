@@ -0,0 +1,24 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Spread is the gap between buying a trading pair on one exchange and selling it on another.
+type Spread struct {
+	TradingPair      string          // e.g. "BTC/USDT"
+	BuyExchange      Exchange        // Exchange with the lowest sell price
+	SellExchange     Exchange        // Exchange with the highest buy price
+	BuyPrice         decimal.Decimal // Best sell price on BuyExchange
+	SellPrice        decimal.Decimal // Best buy price on SellExchange
+	Spread           decimal.Decimal // SellPrice - BuyPrice
+	SpreadPercent    decimal.Decimal // Spread as a percentage of BuyPrice
+	BuyFee           decimal.Decimal // Taker fee rate on BuyExchange
+	SellFee          decimal.Decimal // Taker fee rate on SellExchange
+	NetSpread        decimal.Decimal // Profit per unit after fees
+	NetSpreadPercent decimal.Decimal // NetSpread as a percentage of BuyPrice
+	BuyQuoteAge      time.Duration   // Age of the BuyExchange market data
+	SellQuoteAge     time.Duration   // Age of the SellExchange market data
+}
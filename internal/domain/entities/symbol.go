@@ -0,0 +1,82 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SymbolFormat is how an exchange writes trading pairs as symbols.
+type SymbolFormat struct {
+	Separator string           // Between the base and quote, e.g. "-" for BTC-USDT. Empty for BTCUSDT
+	Aliases   map[string]Asset // Asset codes only used by the exchange, checked before the common aliases
+}
+
+var symbolFormats = map[Exchange]SymbolFormat{
+	ExchangeBinance:  {},
+	ExchangeBybit:    {},
+	ExchangeCoinbase: {Separator: "-"},
+	ExchangeKraken:   {Separator: "/", Aliases: krakenAliases},
+	ExchangeKuCoin:   {Separator: "-"},
+	ExchangeOKX:      {Separator: "-"},
+}
+
+// krakenAliases are the asset codes of Kraken's older APIs, which prefix some assets with X (crypto) or Z (fiat).
+// These are listed explicitly, as plenty of current assets legitimately start with X or Z (e.g. XTZ, ZRX).
+var krakenAliases = map[string]Asset{
+	// Legacy X prefixed crypto
+	"XETC": "ETC",
+	"XETH": "ETH",
+	"XLTC": "LTC",
+	"XMLN": "MLN",
+	"XREP": "REP",
+	"XXBT": "BTC",
+	"XXDG": "DOGE",
+	"XXLM": "XLM",
+	"XXMR": "XMR",
+	"XXRP": "XRP",
+	"XZEC": "ZEC",
+
+	// Legacy Z prefixed fiat
+	"ZAUD": "AUD",
+	"ZCAD": "CAD",
+	"ZEUR": "EUR",
+	"ZGBP": "GBP",
+	"ZJPY": "JPY",
+	"ZUSD": "USD",
+}
+
+// EncodeSymbol returns the exchange's symbol for a trading pair, e.g. BTC/USDT --> BTCUSDT on Binance.
+func (e Exchange) EncodeSymbol(p Pair) string {
+	return string(p.Base) + symbolFormats[e].Separator + string(p.Quote)
+}
+
+// DecodeSymbol returns the trading pair for an exchange symbol, e.g. XBT/USD --> BTC/USD on Kraken.
+// Symbols with no separator, e.g. BTCUSDT on Binance, can't be split reliably, so they fail. Map them from the symbols
+// the exchange was subscribed with instead.
+func (e Exchange) DecodeSymbol(symbol string) (Pair, error) {
+	f := symbolFormats[e]
+	if f.Separator == "" {
+		return Pair{}, fmt.Errorf("%s symbols have no separator, can't decode %s", e, symbol)
+	}
+
+	base, quote, ok := strings.Cut(strings.ToUpper(symbol), f.Separator)
+	if !ok {
+		return Pair{}, fmt.Errorf("invalid %s symbol %s", e, symbol)
+	}
+
+	p, err := NewPair(f.alias(base), f.alias(quote))
+	if err != nil {
+		return Pair{}, fmt.Errorf("invalid %s symbol %s: %w", e, symbol, err)
+	}
+
+	return p, nil
+}
+
+// alias returns the common code for an asset code only used by the exchange.
+func (f SymbolFormat) alias(code string) string {
+	if asset, ok := f.Aliases[code]; ok {
+		return string(asset)
+	}
+
+	return code
+}
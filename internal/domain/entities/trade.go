@@ -0,0 +1,17 @@
+package entities
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Trade is a single trade of a trading pair on an exchange.
+type Trade struct {
+	TradingPair string // e.g. "BTC/USDT"
+	Exchange    Exchange
+	ID          int64 // The exchange's trade ID, increasing with each trade, or zero if its IDs aren't numbers
+	Price       decimal.Decimal
+	Quantity    decimal.Decimal // In the base asset
+	Timestamp   time.Time
+}
@@ -5,6 +5,15 @@ import (
 )
 
 var (
+	ErrExchangeNotFound       = errors.New("exchange not found")
 	ErrMarketNotFound         = errors.New("market not found")
 	ErrInvalidMarketTimestamp = errors.New("market timestamp invalid")
+	ErrOrderBookNotFound      = errors.New("order book not found")
+	ErrStreamingUnavailable   = errors.New("market streaming unavailable")
+	ErrHistoryUnavailable     = errors.New("market history unavailable")
+	ErrInvalidTimeRange       = errors.New("invalid time range")
+	ErrCandlesUnavailable     = errors.New("candles unavailable")
+	ErrInvalidCandleInterval  = errors.New("invalid candle interval")
+	ErrInstrumentNotFound     = errors.New("instrument not found")
+	ErrInstrumentsUnavailable = errors.New("instruments unavailable")
 )
@@ -0,0 +1,36 @@
+package usecases
+
+import (
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+)
+
+// newMarketCandles returns a candle for every interval of a single market update, to be merged into the stored
+// candles. They have no volume, as Volume24hr is a rolling total that also falls as trades leave its window, so
+// volume is only added by trades.
+func newMarketCandles(market entities.Market) []entities.Candle {
+	candles := make([]entities.Candle, 0, len(entities.CandleIntervals))
+
+	for _, interval := range entities.CandleIntervals {
+		candles = append(candles, entities.NewCandle(market.TradingPair, market.Exchange, interval, market.Timestamp, market.LastTradedPrice))
+	}
+
+	return candles
+}
+
+// newTradeCandles returns a candle for every interval of a single trade, to be merged into the stored candles, with
+// the quote asset traded as its volume.
+func newTradeCandles(trade entities.Trade) []entities.Candle {
+	volume := trade.Price.Mul(trade.Quantity).InexactFloat64()
+
+	candles := make([]entities.Candle, 0, len(entities.CandleIntervals))
+
+	for _, interval := range entities.CandleIntervals {
+		c := entities.NewCandle(trade.TradingPair, trade.Exchange, interval, trade.Timestamp, trade.Price)
+		c.Volume = volume
+		c.LastTradeID = trade.ID
+
+		candles = append(candles, c)
+	}
+
+	return candles
+}
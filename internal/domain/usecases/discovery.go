@@ -0,0 +1,90 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+)
+
+// Discovery finds the trading pairs listed on enough exchanges to be worth watching for arbitrage.
+type Discovery struct {
+	minExchanges int
+	sources      []ListingSource
+}
+
+type DiscoveryConfig struct {
+	MinExchanges int // Exchanges a pair must pass the filter on, defaults to 2
+	Sources      []ListingSource
+}
+
+// ListingFilter is the listings considered by Discover. Empty fields match every listing.
+type ListingFilter struct {
+	QuoteAssets   []string                 // e.g. ["USDT", "USDC"]
+	MinVolume24hr float64                  // In the quote asset
+	Statuses      []entities.ListingStatus // e.g. [trading]
+}
+
+func NewDiscovery(cfg DiscoveryConfig) *Discovery {
+	if cfg.MinExchanges == 0 {
+		// Default, arbitrage needs two venues
+		cfg.MinExchanges = 2
+	}
+
+	return &Discovery{
+		minExchanges: cfg.MinExchanges,
+		sources:      cfg.Sources,
+	}
+}
+
+// Discover returns the trading pairs of each exchange that pass the filter on at least MinExchanges exchanges, sorted.
+// Every source's exchange is included, even with no pairs, so its previous pairs can be replaced.
+func (d *Discovery) Discover(ctx context.Context, filter ListingFilter) (map[entities.Exchange][]string, error) {
+	exchangesByPair := make(map[string][]entities.Exchange)
+	pairs := make(map[entities.Exchange][]string, len(d.sources))
+
+	for _, source := range d.sources {
+		pairs[source.Exchange()] = []string{}
+
+		listings, err := source.GetListings(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get listings from %s: %w", source.Exchange(), err)
+		}
+
+		for _, l := range listings {
+			if filter.matches(l) && !slices.Contains(exchangesByPair[l.TradingPair], l.Exchange) {
+				exchangesByPair[l.TradingPair] = append(exchangesByPair[l.TradingPair], l.Exchange)
+			}
+		}
+	}
+
+	for pair, exchanges := range exchangesByPair {
+		if len(exchanges) < d.minExchanges {
+			continue
+		}
+
+		for _, e := range exchanges {
+			pairs[e] = append(pairs[e], pair)
+		}
+	}
+
+	for _, p := range pairs {
+		sort.Strings(p)
+	}
+
+	return pairs, nil
+}
+
+func (f ListingFilter) matches(l entities.Listing) bool {
+	if len(f.QuoteAssets) > 0 && !slices.Contains(f.QuoteAssets, l.Quote) {
+		return false
+	}
+
+	if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, l.Status) {
+		return false
+	}
+
+	return l.Volume24hr >= f.MinVolume24hr
+}
@@ -0,0 +1,98 @@
+package usecases_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/domain/usecases"
+	"github.com/peterstirrup/arbenheimer/internal/domain/usecases/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func newListing(e entities.Exchange, base, quote string, status entities.ListingStatus, volume float64) entities.Listing {
+	return entities.Listing{
+		Exchange:    e,
+		TradingPair: base + "/" + quote,
+		Base:        base,
+		Quote:       quote,
+		Status:      status,
+		Volume24hr:  volume,
+	}
+}
+
+func TestDiscovery_Discover(t *testing.T) {
+	setup := func(t *testing.T) (*mocks.MockListingSource, *mocks.MockListingSource, *usecases.Discovery) {
+		ctrl := gomock.NewController(t)
+		binance := mocks.NewMockListingSource(ctrl)
+		kucoin := mocks.NewMockListingSource(ctrl)
+
+		return binance, kucoin, usecases.NewDiscovery(usecases.DiscoveryConfig{
+			Sources: []usecases.ListingSource{binance, kucoin},
+		})
+	}
+
+	t.Run("finds pairs passing the filter on two exchanges", func(t *testing.T) {
+		binance, kucoin, d := setup(t)
+
+		binance.EXPECT().Exchange().Return(entities.ExchangeBinance).AnyTimes()
+		kucoin.EXPECT().Exchange().Return(entities.ExchangeKuCoin).AnyTimes()
+
+		binance.EXPECT().GetListings(ctx).Return([]entities.Listing{
+			newListing(entities.ExchangeBinance, "BTC", "USDT", entities.ListingStatusTrading, 5e9),
+			newListing(entities.ExchangeBinance, "ETH", "USDT", entities.ListingStatusTrading, 1e9),
+			newListing(entities.ExchangeBinance, "ETH", "BTC", entities.ListingStatusTrading, 1e9),   // Quote asset
+			newListing(entities.ExchangeBinance, "SOL", "USDT", entities.ListingStatusTrading, 1e9),  // Only on Binance
+			newListing(entities.ExchangeBinance, "LUNA", "USDT", entities.ListingStatusHalted, 1e9),  // Halted
+			newListing(entities.ExchangeBinance, "DOGE", "USDT", entities.ListingStatusTrading, 1e9), // Low volume on KuCoin
+		}, nil)
+		kucoin.EXPECT().GetListings(ctx).Return([]entities.Listing{
+			newListing(entities.ExchangeKuCoin, "ETH", "USDT", entities.ListingStatusTrading, 1e8),
+			newListing(entities.ExchangeKuCoin, "BTC", "USDT", entities.ListingStatusTrading, 1e9),
+			newListing(entities.ExchangeKuCoin, "ETH", "BTC", entities.ListingStatusTrading, 1e8),
+			newListing(entities.ExchangeKuCoin, "XMR", "USDT", entities.ListingStatusTrading, 1e8), // Only on KuCoin
+			newListing(entities.ExchangeKuCoin, "LUNA", "USDT", entities.ListingStatusTrading, 1e8),
+			newListing(entities.ExchangeKuCoin, "DOGE", "USDT", entities.ListingStatusTrading, 1e3),
+		}, nil)
+
+		pairs, err := d.Discover(ctx, usecases.ListingFilter{
+			QuoteAssets:   []string{"USDT"},
+			MinVolume24hr: 1e6,
+			Statuses:      []entities.ListingStatus{entities.ListingStatusTrading},
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[entities.Exchange][]string{
+			entities.ExchangeBinance: {"BTC/USDT", "ETH/USDT"},
+			entities.ExchangeKuCoin:  {"BTC/USDT", "ETH/USDT"},
+		}, pairs)
+	})
+
+	t.Run("fails if an exchange's listings can't be fetched", func(t *testing.T) {
+		binance, _, d := setup(t)
+
+		binance.EXPECT().GetListings(ctx).Return(nil, errors.New("timeout"))
+		binance.EXPECT().Exchange().Return(entities.ExchangeBinance).AnyTimes()
+
+		_, err := d.Discover(ctx, usecases.ListingFilter{})
+		require.EqualError(t, err, "failed to get listings from binance: timeout")
+	})
+
+	t.Run("includes exchanges with no pairs", func(t *testing.T) {
+		binance, kucoin, d := setup(t)
+
+		binance.EXPECT().Exchange().Return(entities.ExchangeBinance).AnyTimes()
+		kucoin.EXPECT().Exchange().Return(entities.ExchangeKuCoin).AnyTimes()
+		binance.EXPECT().GetListings(ctx).Return([]entities.Listing{
+			newListing(entities.ExchangeBinance, "BTC", "USDT", entities.ListingStatusTrading, 5e9),
+		}, nil)
+		kucoin.EXPECT().GetListings(ctx).Return(nil, nil)
+
+		pairs, err := d.Discover(ctx, usecases.ListingFilter{})
+		require.NoError(t, err)
+		require.Equal(t, map[entities.Exchange][]string{
+			entities.ExchangeBinance: {},
+			entities.ExchangeKuCoin:  {},
+		}, pairs)
+	})
+}
@@ -0,0 +1,116 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	arberrors "github.com/peterstirrup/arbenheimer/internal/domain/errors"
+	"github.com/rs/zerolog/log"
+)
+
+// Instruments is a registry of the order constraints of every trading pair on each exchange, refreshed from their
+// sources. Opportunities found from markets have to be rounded to them before they can be executed.
+type Instruments struct {
+	refreshInterval time.Duration
+	sources         []InstrumentSource
+
+	mu          sync.RWMutex
+	instruments map[entities.Exchange]map[string]entities.Instrument // By exchange, then trading pair
+}
+
+type InstrumentsConfig struct {
+	RefreshInterval time.Duration // How often the sources are fetched, defaults to 1h
+	Sources         []InstrumentSource
+}
+
+func NewInstruments(cfg InstrumentsConfig) *Instruments {
+	if cfg.RefreshInterval == 0 {
+		// Default, constraints rarely change
+		cfg.RefreshInterval = time.Hour
+	}
+
+	return &Instruments{
+		refreshInterval: cfg.RefreshInterval,
+		sources:         cfg.Sources,
+		instruments:     make(map[entities.Exchange]map[string]entities.Instrument),
+	}
+}
+
+// Run refreshes the instruments every refresh interval, starting straight away, and blocks until the context is
+// cancelled. Failures are logged and retried on the next refresh.
+func (i *Instruments) Run(ctx context.Context) error {
+	t := time.NewTicker(i.refreshInterval)
+	defer t.Stop()
+
+	for {
+		if err := i.Refresh(ctx); err != nil {
+			log.Err(err).Msg("Failed to refresh instruments")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Refresh gets the instruments from every source. A source that fails keeps its previous instruments, so one exchange
+// being down doesn't lose the constraints we already have.
+func (i *Instruments) Refresh(ctx context.Context) error {
+	var errs []error
+
+	for _, source := range i.sources {
+		instruments, err := source.GetInstruments(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get instruments from %s: %w", source.Exchange(), err))
+			continue
+		}
+
+		byPair := make(map[string]entities.Instrument, len(instruments))
+		for _, instrument := range instruments {
+			byPair[instrument.TradingPair] = instrument
+		}
+
+		i.mu.Lock()
+		i.instruments[source.Exchange()] = byPair
+		i.mu.Unlock()
+	}
+
+	return errors.Join(errs...)
+}
+
+// GetInstruments returns the instruments for the given trading pair on each of the given exchanges, or on all
+// exchanges if none are given. If the trading pair is not found on any of them, an error is returned.
+func (i *Instruments) GetInstruments(_ context.Context, tradingPair string, exchanges []entities.Exchange) ([]entities.Instrument, error) {
+	if len(exchanges) == 0 {
+		exchanges = entities.Exchanges
+	}
+
+	for _, e := range exchanges {
+		if !slices.Contains(entities.Exchanges, e) {
+			return nil, fmt.Errorf("%w: %s", arberrors.ErrExchangeNotFound, e)
+		}
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	var instruments []entities.Instrument
+	for _, e := range exchanges {
+		if instrument, ok := i.instruments[e][tradingPair]; ok {
+			instruments = append(instruments, instrument)
+		}
+	}
+
+	if len(instruments) == 0 {
+		return nil, arberrors.ErrInstrumentNotFound
+	}
+
+	return instruments, nil
+}
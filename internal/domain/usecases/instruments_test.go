@@ -0,0 +1,84 @@
+package usecases_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	arberrors "github.com/peterstirrup/arbenheimer/internal/domain/errors"
+	"github.com/peterstirrup/arbenheimer/internal/domain/usecases"
+	"github.com/peterstirrup/arbenheimer/internal/domain/usecases/mocks"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstruments(t *testing.T) {
+	btcBinance := entities.Instrument{
+		Exchange:    entities.ExchangeBinance,
+		TradingPair: "BTC/USDT",
+		Status:      entities.ListingStatusTrading,
+		TickSize:    decimal.RequireFromString("0.01"),
+	}
+	btcKuCoin := entities.Instrument{
+		Exchange:    entities.ExchangeKuCoin,
+		TradingPair: "BTC/USDT",
+		Status:      entities.ListingStatusTrading,
+		TickSize:    decimal.RequireFromString("0.1"),
+	}
+
+	setup := func(t *testing.T) (*mocks.MockInstrumentSource, *mocks.MockInstrumentSource, *usecases.Instruments) {
+		ctrl := gomock.NewController(t)
+		binance := mocks.NewMockInstrumentSource(ctrl)
+		kucoin := mocks.NewMockInstrumentSource(ctrl)
+
+		binance.EXPECT().Exchange().Return(entities.ExchangeBinance).AnyTimes()
+		kucoin.EXPECT().Exchange().Return(entities.ExchangeKuCoin).AnyTimes()
+
+		return binance, kucoin, usecases.NewInstruments(usecases.InstrumentsConfig{
+			Sources: []usecases.InstrumentSource{binance, kucoin},
+		})
+	}
+
+	t.Run("returns the instruments on each exchange", func(t *testing.T) {
+		binance, kucoin, i := setup(t)
+
+		binance.EXPECT().GetInstruments(ctx).Return([]entities.Instrument{btcBinance}, nil)
+		kucoin.EXPECT().GetInstruments(ctx).Return([]entities.Instrument{btcKuCoin}, nil)
+		require.NoError(t, i.Refresh(ctx))
+
+		instruments, err := i.GetInstruments(ctx, "BTC/USDT", nil)
+		require.NoError(t, err)
+		require.Equal(t, []entities.Instrument{btcBinance, btcKuCoin}, instruments)
+
+		instruments, err = i.GetInstruments(ctx, "BTC/USDT", []entities.Exchange{entities.ExchangeKuCoin})
+		require.NoError(t, err)
+		require.Equal(t, []entities.Instrument{btcKuCoin}, instruments)
+
+		_, err = i.GetInstruments(ctx, "ETH/USDT", nil)
+		require.ErrorIs(t, err, arberrors.ErrInstrumentNotFound)
+	})
+
+	t.Run("fails for an unknown exchange", func(t *testing.T) {
+		_, _, i := setup(t)
+
+		_, err := i.GetInstruments(ctx, "BTC/USDT", []entities.Exchange{"unknown"})
+		require.ErrorIs(t, err, arberrors.ErrExchangeNotFound)
+	})
+
+	t.Run("keeps the previous instruments of a failing source", func(t *testing.T) {
+		binance, kucoin, i := setup(t)
+
+		binance.EXPECT().GetInstruments(ctx).Return([]entities.Instrument{btcBinance}, nil)
+		kucoin.EXPECT().GetInstruments(ctx).Return([]entities.Instrument{btcKuCoin}, nil)
+		require.NoError(t, i.Refresh(ctx))
+
+		binance.EXPECT().GetInstruments(ctx).Return(nil, errors.New("timeout"))
+		kucoin.EXPECT().GetInstruments(ctx).Return(nil, nil)
+		require.EqualError(t, i.Refresh(ctx), "failed to get instruments from binance: timeout")
+
+		instruments, err := i.GetInstruments(ctx, "BTC/USDT", nil)
+		require.NoError(t, err)
+		require.Equal(t, []entities.Instrument{btcBinance}, instruments)
+	})
+}
@@ -1,2 +1,7 @@
 //go:generate sh -c "test store.go -nt $GOFILE && exit 0; mockgen -destination=./store.go -package=mocks github.com/peterstirrup/arbenheimer/internal/domain/usecases Store"
+//go:generate sh -c "test broker.go -nt $GOFILE && exit 0; mockgen -destination=./broker.go -package=mocks github.com/peterstirrup/arbenheimer/internal/domain/usecases Broker"
+//go:generate sh -c "test history.go -nt $GOFILE && exit 0; mockgen -destination=./history.go -package=mocks github.com/peterstirrup/arbenheimer/internal/domain/usecases History"
+//go:generate sh -c "test candlestore.go -nt $GOFILE && exit 0; mockgen -destination=./candlestore.go -package=mocks github.com/peterstirrup/arbenheimer/internal/domain/usecases CandleStore"
+//go:generate sh -c "test listingsource.go -nt $GOFILE && exit 0; mockgen -destination=./listingsource.go -package=mocks github.com/peterstirrup/arbenheimer/internal/domain/usecases ListingSource"
+//go:generate sh -c "test instrumentsource.go -nt $GOFILE && exit 0; mockgen -destination=./instrumentsource.go -package=mocks github.com/peterstirrup/arbenheimer/internal/domain/usecases InstrumentSource"
 package mocks
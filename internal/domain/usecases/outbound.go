@@ -2,11 +2,57 @@ package usecases
 
 import (
 	"context"
+	"time"
 
 	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
 )
 
 type Store interface {
-	GetMarket(ctx context.Context, exchange entities.Exchange, tradingPair string) (entities.Market, error)
-	UpdateMarket(ctx context.Context, market entities.Market) error
+	// GetMarkets returns the markets for every combination of the given exchanges and trading pairs in one round trip,
+	// ordered by trading pair then exchange. Markets that aren't found are skipped.
+	GetMarkets(ctx context.Context, exchanges []entities.Exchange, tradingPairs []string) ([]entities.Market, error)
+	// UpdateMarketIfNewer stores the market unless the stored market has a later timestamp, in which case
+	// it returns ErrInvalidMarketTimestamp. The check and write are atomic.
+	UpdateMarketIfNewer(ctx context.Context, market entities.Market) error
+	GetOrderBook(ctx context.Context, exchange entities.Exchange, tradingPair string) (entities.OrderBook, error)
+	// UpdateOrderBookIfNewer stores the order book unless the stored order book has a later timestamp, in which case
+	// it returns ErrInvalidMarketTimestamp. The check and write are atomic.
+	UpdateOrderBookIfNewer(ctx context.Context, orderBook entities.OrderBook) error
+}
+
+// Broker carries market updates between processes, e.g. from the updaters to the server.
+type Broker interface {
+	PublishMarket(ctx context.Context, market entities.Market) error
+	// SubscribeMarkets returns a channel of market updates for the given exchanges and trading pairs.
+	// Empty exchanges or trading pairs match all. The channel is closed when the context is cancelled.
+	SubscribeMarkets(ctx context.Context, exchanges []entities.Exchange, tradingPairs []string) (<-chan entities.Market, error)
+}
+
+// History keeps every accepted market update, unlike the Store which only keeps the latest.
+type History interface {
+	AppendMarket(ctx context.Context, market entities.Market) error
+	// GetMarketHistory returns the first limit markets timestamped from from to to inclusive, oldest first.
+	GetMarketHistory(ctx context.Context, exchange entities.Exchange, tradingPair string, from, to time.Time, limit int) ([]entities.Market, error)
+}
+
+// CandleStore persists candles as they're built.
+type CandleStore interface {
+	// UpdateCandles merges candles into the stored ones with the same exchange, trading pair, interval and open time,
+	// as Candle.Merge does, storing them if there aren't any. Each merge is atomic, so updaters of the same market
+	// can't overwrite each other's updates.
+	UpdateCandles(ctx context.Context, candles []entities.Candle) error
+	// GetCandles returns the candles opened from from to to inclusive, oldest first.
+	GetCandles(ctx context.Context, exchange entities.Exchange, tradingPair string, interval entities.CandleInterval, from, to time.Time) ([]entities.Candle, error)
+}
+
+// ListingSource lists the trading pairs on an exchange, e.g. from its REST API.
+type ListingSource interface {
+	Exchange() entities.Exchange
+	GetListings(ctx context.Context) ([]entities.Listing, error)
+}
+
+// InstrumentSource gets the order constraints of the trading pairs on an exchange, e.g. from its REST API.
+type InstrumentSource interface {
+	Exchange() entities.Exchange
+	GetInstruments(ctx context.Context) ([]entities.Instrument, error)
 }
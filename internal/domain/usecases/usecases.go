@@ -4,67 +4,588 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
 	arberrors "github.com/peterstirrup/arbenheimer/internal/domain/errors"
 	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/peterstirrup/arbenheimer/internal/domain/usecases")
+
+// maxMarketHistory is the most markets GetMarketHistory returns at once, so a request for a week of history can't
+// read every update into memory.
+const maxMarketHistory = 10000
+
 type Market struct {
-	store   Store
-	timeNow func() time.Time // Need to be deterministic for testing
+	broker      Broker
+	candles     CandleStore
+	fees        entities.FeeSchedule
+	history     History
+	instruments *Instruments
+	staleAfter  time.Duration
+	store       Store
+	timeNow     func() time.Time // Need to be deterministic for testing
+
+	// Changed by SetTradingPairs while running.
+	pairsMu      sync.RWMutex
+	tradingPairs []string
 }
 
 type MarketConfig struct {
-	Store   Store
-	TimeNow func() time.Time
+	Broker       Broker               // Optional, markets aren't published or streamed without it
+	Candles      CandleStore          // Optional, candles aren't built without it
+	Fees         entities.FeeSchedule // Optional, spreads are fee free without it
+	History      History              // Optional, market history isn't kept without it
+	Instruments  *Instruments         // Optional, executable arbitrage isn't rounded to order constraints without it
+	StaleAfter   time.Duration        // Age after which a quote is stale, defaults to 1 minute
+	Store        Store
+	TimeNow      func() time.Time
+	TradingPairs []string // Pairs used when a request doesn't specify any, e.g. ["BTC/USDT", "ETH/USDT"]
 }
 
 func NewMarket(cfg MarketConfig) *Market {
+	if cfg.StaleAfter == 0 {
+		// Default, well within the store's market TTL
+		cfg.StaleAfter = time.Minute
+	}
+
 	return &Market{
-		store:   cfg.Store,
-		timeNow: cfg.TimeNow,
+		broker:       cfg.Broker,
+		candles:      cfg.Candles,
+		fees:         cfg.Fees,
+		history:      cfg.History,
+		instruments:  cfg.Instruments,
+		staleAfter:   cfg.StaleAfter,
+		store:        cfg.Store,
+		timeNow:      cfg.TimeNow,
+		tradingPairs: cfg.TradingPairs,
 	}
 }
 
-// GetMarkets returns the market data for the given trading pair from all exchanges.
-// If the trading pair is not found on any exchange, an error is returned.
-func (m *Market) GetMarkets(ctx context.Context, tradingPair string) ([]entities.Market, error) {
-	var markets []entities.Market
-	var found bool
+// SetTradingPairs replaces the pairs used when a request doesn't specify any, e.g. when trading_pairs.yaml changes.
+func (m *Market) SetTradingPairs(pairs []string) error {
+	m.pairsMu.Lock()
+	defer m.pairsMu.Unlock()
+
+	m.tradingPairs = pairs
+	return nil
+}
+
+// defaultTradingPairs returns the pairs used when a request doesn't specify any.
+func (m *Market) defaultTradingPairs() []string {
+	m.pairsMu.RLock()
+	defer m.pairsMu.RUnlock()
+
+	return m.tradingPairs
+}
+
+// GetMarkets returns the quotes for the given trading pair from all exchanges, leaving out stale quotes if excludeStale
+// is set. If the trading pair is not found on any exchange, an error is returned.
+func (m *Market) GetMarkets(ctx context.Context, tradingPair string, excludeStale bool) ([]entities.Quote, error) {
+	markets, err := m.store.GetMarkets(ctx, entities.Exchanges, []string{tradingPair})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(markets) == 0 {
+		return nil, arberrors.ErrMarketNotFound
+	}
+
+	quotes := m.toQuotes(markets, excludeStale)
+	if len(quotes) == 0 {
+		return nil, fmt.Errorf("%w: every quote for %s is stale", arberrors.ErrMarketNotFound, tradingPair)
+	}
+
+	return quotes, nil
+}
+
+// GetMarketsForPairs returns the quotes for each of the given trading pairs from all exchanges, ordered by trading
+// pair then exchange, leaving out stale quotes if excludeStale is set. If no trading pairs are given, the configured
+// pairs are used. Pairs that aren't found are skipped.
+func (m *Market) GetMarketsForPairs(ctx context.Context, tradingPairs []string, excludeStale bool) ([]entities.Quote, error) {
+	if len(tradingPairs) == 0 {
+		tradingPairs = m.defaultTradingPairs()
+	}
+
+	markets, err := m.store.GetMarkets(ctx, entities.Exchanges, tradingPairs)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.toQuotes(markets, excludeStale), nil
+}
+
+// CheckFreshness returns an error if none of the exchange's markets for the configured trading pairs have been updated
+// within the stale age, e.g. because its updater has stopped.
+func (m *Market) CheckFreshness(ctx context.Context, exchange entities.Exchange) error {
+	markets, err := m.store.GetMarkets(ctx, []entities.Exchange{exchange}, m.defaultTradingPairs())
+	if err != nil {
+		return err
+	}
+
+	if len(markets) == 0 {
+		return fmt.Errorf("%w: no markets on %s", arberrors.ErrMarketNotFound, exchange)
+	}
+
+	latest := markets[0].Timestamp
+	for _, market := range markets[1:] {
+		if market.Timestamp.After(latest) {
+			latest = market.Timestamp
+		}
+	}
+
+	if age := m.timeNow().Sub(latest); age > m.staleAfter {
+		return fmt.Errorf("latest market on %s is %s old", exchange, age.Round(time.Second))
+	}
+
+	return nil
+}
 
-	for _, exchange := range entities.Exchanges {
-		m, err := m.store.GetMarket(ctx, exchange, tradingPair)
+// toQuotes works out how long ago each market was updated, leaving out stale markets if excludeStale is set.
+func (m *Market) toQuotes(markets []entities.Market, excludeStale bool) []entities.Quote {
+	now := m.timeNow()
+
+	quotes := make([]entities.Quote, 0, len(markets))
+	for _, market := range markets {
+		age := now.Sub(market.Timestamp)
+		stale := age > m.staleAfter
+		if stale && excludeStale {
+			continue
+		}
+
+		quotes = append(quotes, entities.Quote{Market: market, Age: age, Stale: stale})
+	}
+
+	return quotes
+}
+
+// UpdateMarket updates the market data in the store, appends it to the history, adds it to the candles and publishes
+// it to any subscribers.
+// If the market data is older than the current data in the store, it will not be updated.
+func (m *Market) UpdateMarket(ctx context.Context, market entities.Market) error {
+	ctx, span := tracer.Start(ctx, "usecases.UpdateMarket", trace.WithAttributes(
+		attribute.String("exchange", market.Exchange.String()),
+		attribute.String("trading_pair", market.TradingPair),
+	))
+	defer span.End()
+
+	// Checked and written atomically, so redundant updaters can't overwrite newer data
+	err := m.store.UpdateMarketIfNewer(ctx, market)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	if m.history != nil {
+		// The store is the source of truth, a gap in the history shouldn't stop live updates
+		if err = m.history.AppendMarket(ctx, market); err != nil {
+			log.Warn().Interface("exchange", market.Exchange).Err(err).Msgf("failed to append market data to history for trading pair %s", market.TradingPair)
+		}
+	}
+
+	if m.candles != nil {
+		if err = m.candles.UpdateCandles(ctx, newMarketCandles(market)); err != nil {
+			log.Warn().Interface("exchange", market.Exchange).Err(err).Msgf("failed to update candles for trading pair %s", market.TradingPair)
+		}
+	}
+
+	if m.broker != nil {
+		// The store is the source of truth, subscribers will catch up on the next update
+		if err = m.broker.PublishMarket(ctx, market); err != nil {
+			log.Warn().Interface("exchange", market.Exchange).Err(err).Msgf("failed to publish market data for trading pair %s", market.TradingPair)
+		}
+	}
+
+	return nil
+}
+
+// GetOrderBooks returns the order books for the given trading pair from the given exchanges, or all exchanges if none
+// are given. If the order book is not found on any exchange, an error is returned.
+func (m *Market) GetOrderBooks(ctx context.Context, tradingPair string, exchanges []entities.Exchange) ([]entities.OrderBook, error) {
+	if len(exchanges) == 0 {
+		exchanges = entities.Exchanges
+	}
+
+	var orderBooks []entities.OrderBook
+
+	for _, exchange := range exchanges {
+		if !slices.Contains(entities.Exchanges, exchange) {
+			return nil, fmt.Errorf("%w: %s", arberrors.ErrExchangeNotFound, exchange)
+		}
+
+		ob, err := m.store.GetOrderBook(ctx, exchange, tradingPair)
 		if err != nil {
-			if !errors.Is(err, arberrors.ErrMarketNotFound) {
-				log.Warn().Interface("exchange", exchange).Err(err).Msgf("failed to get market data for trading pair %s", tradingPair)
+			if !errors.Is(err, arberrors.ErrOrderBookNotFound) {
+				log.Warn().Interface("exchange", exchange).Err(err).Msgf("failed to get order book for trading pair %s", tradingPair)
 			}
 			continue
 		}
 
-		markets = append(markets, m)
-		found = true
+		orderBooks = append(orderBooks, ob)
 	}
 
-	if !found {
-		return nil, arberrors.ErrMarketNotFound
+	if len(orderBooks) == 0 {
+		return nil, arberrors.ErrOrderBookNotFound
 	}
 
-	return markets, nil
+	return orderBooks, nil
 }
 
-// UpdateMarket updates the market data in the store.
-// If the market data is older than the current data in the store, it will not be updated.
-func (m *Market) UpdateMarket(ctx context.Context, market entities.Market) error {
-	currMarket, err := m.store.GetMarket(ctx, market.Exchange, market.TradingPair)
-	if err != nil && !errors.Is(err, arberrors.ErrMarketNotFound) {
+// GetExecutableArbitrage returns the largest profitable trade from buying the trading pair on one exchange and selling
+// it on another, for each pair of exchanges with a profitable trade, ordered by profit (highest first).
+// Both order books are walked level by level until the next unit would no longer be profitable after fees.
+// If maxNotional is positive, no more than maxNotional of the quote asset is spent buying.
+// If the market has instruments, the quantity is rounded to both exchanges' order constraints, and trades either
+// exchange wouldn't accept, or whose constraints aren't known, are left out.
+func (m *Market) GetExecutableArbitrage(ctx context.Context, tradingPair string, maxNotional decimal.Decimal) ([]entities.Arbitrage, error) {
+	orderBooks, err := m.GetOrderBooks(ctx, tradingPair, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var arbitrages []entities.Arbitrage
+
+	for _, buy := range orderBooks {
+		for _, sell := range orderBooks {
+			if sell.Exchange == buy.Exchange {
+				continue
+			}
+
+			arb := m.sizeArbitrage(buy, sell, maxNotional, decimal.Zero)
+			if !arb.Quantity.IsPositive() {
+				continue
+			}
+
+			if m.instruments != nil {
+				var ok bool
+				if arb, ok = m.roundArbitrage(ctx, buy, sell, maxNotional, arb); !ok {
+					continue
+				}
+			}
+
+			arbitrages = append(arbitrages, arb)
+		}
+	}
+
+	sort.SliceStable(arbitrages, func(i, j int) bool {
+		return arbitrages[i].Profit.GreaterThan(arbitrages[j].Profit)
+	})
+
+	return arbitrages, nil
+}
+
+// roundArbitrage resizes the arbitrage to a quantity both exchanges accept, rounded down to their step sizes and
+// within their max quantities. Returns false if either exchange's instrument isn't known, or the rounded trade is
+// below either exchange's min quantity or min notional, or the pair isn't trading.
+func (m *Market) roundArbitrage(ctx context.Context, buy, sell entities.OrderBook, maxNotional decimal.Decimal, arb entities.Arbitrage) (entities.Arbitrage, bool) {
+	instruments, err := m.instruments.GetInstruments(ctx, arb.TradingPair, []entities.Exchange{buy.Exchange, sell.Exchange})
+	if err != nil {
+		return arb, false
+	}
+
+	var buyInstrument, sellInstrument *entities.Instrument
+	for _, i := range instruments {
+		switch i.Exchange {
+		case buy.Exchange:
+			buyInstrument = &i
+		case sell.Exchange:
+			sellInstrument = &i
+		}
+	}
+
+	if buyInstrument == nil || sellInstrument == nil {
+		return arb, false
+	}
+
+	quantity := arb.Quantity
+	for _, i := range []*entities.Instrument{buyInstrument, sellInstrument} {
+		if i.MaxQuantity.IsPositive() {
+			quantity = decimal.Min(quantity, i.MaxQuantity)
+		}
+	}
+
+	quantity = entities.RoundQuantityForBoth(*buyInstrument, *sellInstrument, quantity)
+	if !quantity.IsPositive() {
+		return arb, false
+	}
+
+	if quantity.LessThan(arb.Quantity) {
+		// Fewer units from the same levels, so every unit is still profitable
+		arb = m.sizeArbitrage(buy, sell, maxNotional, quantity)
+	}
+
+	// The levels are already on each exchange's tick, but their average usually isn't
+	if !buyInstrument.Allows(buyInstrument.RoundPrice(arb.BuyVWAP), arb.Quantity) ||
+		!sellInstrument.Allows(sellInstrument.RoundPrice(arb.SellVWAP), arb.Quantity) {
+		return arb, false
+	}
+
+	return arb, true
+}
+
+// sizeArbitrage walks the asks of the buy order book and the bids of the sell order book, taking liquidity
+// while the sell price still covers the buy price and fees, until maxNotional is spent and maxQuantity is taken if
+// they're positive.
+func (m *Market) sizeArbitrage(buy, sell entities.OrderBook, maxNotional, maxQuantity decimal.Decimal) entities.Arbitrage {
+	arb := entities.Arbitrage{
+		TradingPair:  buy.TradingPair,
+		BuyExchange:  buy.Exchange,
+		SellExchange: sell.Exchange,
+	}
+
+	var i, j int
+	var askFilled, bidFilled decimal.Decimal // Quantity already taken from the current levels
+
+	for i < len(buy.Asks) && j < len(sell.Bids) {
+		ask, bid := buy.Asks[i], sell.Bids[j]
+
+		if !m.fees.NetProfit(buy.Exchange, ask.Price, sell.Exchange, bid.Price).IsPositive() {
+			break
+		}
+
+		quantity := decimal.Min(ask.Quantity.Sub(askFilled), bid.Quantity.Sub(bidFilled))
+
+		limitReached := false
+		if maxNotional.IsPositive() {
+			budget := maxNotional.Sub(arb.BuyNotional).Div(ask.Price)
+			if budget.LessThanOrEqual(quantity) {
+				quantity = budget
+				limitReached = true
+			}
+		}
+
+		if maxQuantity.IsPositive() {
+			remaining := maxQuantity.Sub(arb.Quantity)
+			if remaining.LessThanOrEqual(quantity) {
+				quantity = remaining
+				limitReached = true
+			}
+		}
+
+		arb.Quantity = arb.Quantity.Add(quantity)
+		arb.BuyNotional = arb.BuyNotional.Add(quantity.Mul(ask.Price))
+		arb.SellNotional = arb.SellNotional.Add(quantity.Mul(bid.Price))
+
+		if limitReached {
+			break
+		}
+
+		askFilled = askFilled.Add(quantity)
+		if askFilled.GreaterThanOrEqual(ask.Quantity) {
+			i++
+			askFilled = decimal.Zero
+		}
+
+		bidFilled = bidFilled.Add(quantity)
+		if bidFilled.GreaterThanOrEqual(bid.Quantity) {
+			j++
+			bidFilled = decimal.Zero
+		}
+	}
+
+	if !arb.Quantity.IsPositive() {
+		return arb
+	}
+
+	buyFee := m.fees.Fee(buy.Exchange).Taker
+	sellFee := m.fees.Fee(sell.Exchange).Taker
+
+	arb.BuyVWAP = arb.BuyNotional.Div(arb.Quantity)
+	arb.SellVWAP = arb.SellNotional.Div(arb.Quantity)
+	arb.Profit = arb.SellNotional.Mul(decimal.NewFromInt(1).Sub(sellFee)).Sub(arb.BuyNotional.Mul(decimal.NewFromInt(1).Add(buyFee)))
+
+	return arb
+}
+
+// UpdateOrderBook updates the order book in the store.
+// If the order book is older than the current order book in the store, it will not be updated.
+func (m *Market) UpdateOrderBook(ctx context.Context, orderBook entities.OrderBook) error {
+	// Checked and written atomically, so redundant updaters can't overwrite newer order books
+	return m.store.UpdateOrderBookIfNewer(ctx, orderBook)
+}
+
+// AddTrade adds a trade's price and volume to the candles of its market. Trades aren't stored otherwise, so it does
+// nothing if candles aren't kept.
+func (m *Market) AddTrade(ctx context.Context, trade entities.Trade) error {
+	if m.candles == nil {
+		return nil
+	}
+
+	ctx, span := tracer.Start(ctx, "usecases.AddTrade", trace.WithAttributes(
+		attribute.String("exchange", trade.Exchange.String()),
+		attribute.String("trading_pair", trade.TradingPair),
+	))
+	defer span.End()
+
+	if err := m.candles.UpdateCandles(ctx, newTradeCandles(trade)); err != nil {
+		span.SetStatus(codes.Error, err.Error())
 		return err
 	}
 
-	if currMarket.Timestamp.After(market.Timestamp) {
-		return fmt.Errorf("%w: current market data is newer than the provided data", arberrors.ErrInvalidMarketTimestamp)
+	return nil
+}
+
+// StreamMarkets returns a channel of accepted market updates for the given trading pairs and exchanges.
+// Empty trading pairs or exchanges match all. The channel is closed when the context is cancelled.
+func (m *Market) StreamMarkets(ctx context.Context, tradingPairs []string, exchanges []entities.Exchange) (<-chan entities.Market, error) {
+	if m.broker == nil {
+		return nil, arberrors.ErrStreamingUnavailable
+	}
+
+	for _, e := range exchanges {
+		if !slices.Contains(entities.Exchanges, e) {
+			return nil, fmt.Errorf("%w: %s", arberrors.ErrExchangeNotFound, e)
+		}
+	}
+
+	return m.broker.SubscribeMarkets(ctx, exchanges, tradingPairs)
+}
+
+// GetMarketHistory returns the market data for the given trading pair and exchange timestamped between from and to
+// inclusive, oldest first. No more than limit markets are returned, or maxMarketHistory if limit is zero or over it,
+// so the rest of a long range is read by requesting again from the last market's timestamp.
+func (m *Market) GetMarketHistory(ctx context.Context, tradingPair string, exchange entities.Exchange, from, to time.Time, limit int) ([]entities.Market, error) {
+	if m.history == nil {
+		return nil, arberrors.ErrHistoryUnavailable
+	}
+
+	if !slices.Contains(entities.Exchanges, exchange) {
+		return nil, fmt.Errorf("%w: %s", arberrors.ErrExchangeNotFound, exchange)
+	}
+
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from %s is after to %s", arberrors.ErrInvalidTimeRange, from, to)
+	}
+
+	if limit <= 0 || limit > maxMarketHistory {
+		limit = maxMarketHistory
+	}
+
+	return m.history.GetMarketHistory(ctx, exchange, tradingPair, from, to, limit)
+}
+
+// GetCandles returns the candles for the given trading pair, exchange and interval opened between from and to
+// inclusive, oldest first.
+func (m *Market) GetCandles(ctx context.Context, tradingPair string, exchange entities.Exchange, interval entities.CandleInterval, from, to time.Time) ([]entities.Candle, error) {
+	if m.candles == nil {
+		return nil, arberrors.ErrCandlesUnavailable
+	}
+
+	if !slices.Contains(entities.Exchanges, exchange) {
+		return nil, fmt.Errorf("%w: %s", arberrors.ErrExchangeNotFound, exchange)
+	}
+
+	if !slices.Contains(entities.CandleIntervals, interval) {
+		return nil, fmt.Errorf("%w: %s", arberrors.ErrInvalidCandleInterval, interval)
+	}
+
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: from %s is after to %s", arberrors.ErrInvalidTimeRange, from, to)
 	}
 
-	return m.store.UpdateMarket(ctx, market)
+	return m.candles.GetCandles(ctx, exchange, tradingPair, interval, from, to)
+}
+
+// GetTopSpreads returns the largest spread between exchanges for each of the given trading pairs, ordered by spread
+// percentage after fees (highest first). If no trading pairs are given, the configured pairs are used.
+// Stale quotes are left out, so a feed that's stopped updating can't produce a false spread. Pairs that aren't found on
+// at least two exchanges are skipped. If limit is positive, at most limit spreads are returned.
+func (m *Market) GetTopSpreads(ctx context.Context, tradingPairs []string, limit int) ([]entities.Spread, error) {
+	if len(tradingPairs) == 0 {
+		tradingPairs = m.defaultTradingPairs()
+	}
+
+	quotes, err := m.GetMarketsForPairs(ctx, tradingPairs, true)
+	if err != nil {
+		return nil, err
+	}
+
+	marketsByPair := make(map[string][]entities.Market)
+	for _, q := range quotes {
+		marketsByPair[q.TradingPair] = append(marketsByPair[q.TradingPair], q.Market)
+	}
+
+	var spreads []entities.Spread
+
+	for _, pair := range tradingPairs {
+		spread, ok := m.bestSpread(marketsByPair[pair])
+		if !ok {
+			continue
+		}
+
+		spreads = append(spreads, spread)
+	}
+
+	sort.SliceStable(spreads, func(i, j int) bool {
+		return spreads[i].NetSpreadPercent.GreaterThan(spreads[j].NetSpreadPercent)
+	})
+
+	if limit > 0 && len(spreads) > limit {
+		spreads = spreads[:limit]
+	}
+
+	return spreads, nil
+}
+
+// bestSpread returns the largest spread after fees from buying on one exchange and selling on another.
+// Returns false if there are fewer than two exchanges with usable prices.
+func (m *Market) bestSpread(markets []entities.Market) (entities.Spread, bool) {
+	var best entities.Spread
+	var found bool
+
+	now := m.timeNow()
+
+	for _, buy := range markets {
+		// Buy at the lowest sell price, can't work out a percentage without one
+		if !buy.BestSellPrice.IsPositive() {
+			continue
+		}
+
+		for _, sell := range markets {
+			if sell.Exchange == buy.Exchange || !sell.BestBuyPrice.IsPositive() {
+				continue
+			}
+
+			netSpread := m.fees.NetProfit(buy.Exchange, buy.BestSellPrice, sell.Exchange, sell.BestBuyPrice)
+			netSpreadPercent := percentOf(netSpread, buy.BestSellPrice)
+			if found && !netSpreadPercent.GreaterThan(best.NetSpreadPercent) {
+				continue
+			}
+
+			spread := sell.BestBuyPrice.Sub(buy.BestSellPrice)
+
+			best = entities.Spread{
+				TradingPair:      buy.TradingPair,
+				BuyExchange:      buy.Exchange,
+				SellExchange:     sell.Exchange,
+				BuyPrice:         buy.BestSellPrice,
+				SellPrice:        sell.BestBuyPrice,
+				Spread:           spread,
+				SpreadPercent:    percentOf(spread, buy.BestSellPrice),
+				BuyFee:           m.fees.Fee(buy.Exchange).Taker,
+				SellFee:          m.fees.Fee(sell.Exchange).Taker,
+				NetSpread:        netSpread,
+				NetSpreadPercent: netSpreadPercent,
+				BuyQuoteAge:      now.Sub(buy.Timestamp),
+				SellQuoteAge:     now.Sub(sell.Timestamp),
+			}
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+// percentOf returns v as a percentage of total.
+func percentOf(v, total decimal.Decimal) decimal.Decimal {
+	return v.Div(total).Mul(decimal.NewFromInt(100))
 }
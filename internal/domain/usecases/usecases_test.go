@@ -2,6 +2,8 @@ package usecases_test
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"testing"
 	"time"
 
@@ -40,6 +42,9 @@ var (
 
 type setupMarketTestConfig struct {
 	mockCtrl *gomock.Controller
+	broker   *mocks.MockBroker
+	candles  *mocks.MockCandleStore
+	history  *mocks.MockHistory
 	store    *mocks.MockStore
 
 	market *usecases.Market
@@ -47,91 +52,867 @@ type setupMarketTestConfig struct {
 
 func setupTest(t *testing.T) *setupMarketTestConfig {
 	ctrl := gomock.NewController(t)
+	broker := mocks.NewMockBroker(ctrl)
+	candles := mocks.NewMockCandleStore(ctrl)
+	history := mocks.NewMockHistory(ctrl)
 	store := mocks.NewMockStore(ctrl)
 
 	return &setupMarketTestConfig{
 		mockCtrl: ctrl,
+		broker:   broker,
+		candles:  candles,
+		history:  history,
 		store:    store,
 		market: usecases.NewMarket(usecases.MarketConfig{
+			Broker:  broker,
+			Candles: candles,
+			History: history,
 			Store:   store,
 			TimeNow: func() time.Time { return testTime },
 		}),
 	}
 }
 
+// newCandles returns the candles for every interval of a market update.
+func newCandles(market entities.Market) []entities.Candle {
+	var candles []entities.Candle
+	for _, interval := range entities.CandleIntervals {
+		candles = append(candles, entities.NewCandle(market.TradingPair, market.Exchange, interval, market.Timestamp, market.LastTradedPrice))
+	}
+
+	return candles
+}
+
+// expectOrderBookNotFoundOnOtherExchanges expects the order book for the trading pair to be missing on every exchange
+// except the given ones.
+func (cfg *setupMarketTestConfig) expectOrderBookNotFoundOnOtherExchanges(tradingPair string, exchanges ...entities.Exchange) {
+	for _, e := range entities.Exchanges {
+		if !slices.Contains(exchanges, e) {
+			cfg.store.EXPECT().GetOrderBook(ctx, e, tradingPair).Return(entities.OrderBook{}, arberrors.ErrOrderBookNotFound)
+		}
+	}
+}
+
 func TestMarket_GetMarkets(t *testing.T) {
+	staleKuCoin := marketKuCoin
+	staleKuCoin.Timestamp = testTime.Add(-8 * time.Minute)
+
 	t.Run("gets markets successfully", func(t *testing.T) {
 		cfg := setupTest(t)
 
 		markets := []entities.Market{marketBinance, marketKuCoin}
 
-		cfg.store.EXPECT().GetMarket(ctx, entities.ExchangeBinance, "BTC/USDT").Return(markets[0], nil)
-		cfg.store.EXPECT().GetMarket(ctx, entities.ExchangeKuCoin, "BTC/USDT").Return(markets[1], nil)
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT"}).Return(markets, nil)
 
-		resp, err := cfg.market.GetMarkets(ctx, "BTC/USDT")
+		resp, err := cfg.market.GetMarkets(ctx, "BTC/USDT", false)
 		require.NoError(t, err)
-		require.Len(t, resp, 2)
-		require.Equal(t, markets, resp)
+		require.Equal(t, []entities.Quote{{Market: marketBinance}, {Market: marketKuCoin}}, resp)
 	})
 
-	t.Run("fails to get markets for one exchange, returns found markets", func(t *testing.T) {
+	t.Run("flags stale markets with their age", func(t *testing.T) {
 		cfg := setupTest(t)
 
-		markets := []entities.Market{marketKuCoin}
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT"}).Return([]entities.Market{marketBinance, staleKuCoin}, nil)
 
-		cfg.store.EXPECT().GetMarket(ctx, entities.ExchangeBinance, "BTC/USDT").Return(entities.Market{}, arberrors.ErrMarketNotFound)
-		cfg.store.EXPECT().GetMarket(ctx, entities.ExchangeKuCoin, "BTC/USDT").Return(markets[0], nil)
+		resp, err := cfg.market.GetMarkets(ctx, "BTC/USDT", false)
+		require.NoError(t, err)
+		require.Equal(t, []entities.Quote{
+			{Market: marketBinance},
+			{Market: staleKuCoin, Age: 8 * time.Minute, Stale: true},
+		}, resp)
+	})
+
+	t.Run("excludes stale markets", func(t *testing.T) {
+		cfg := setupTest(t)
 
-		resp, err := cfg.market.GetMarkets(ctx, "BTC/USDT")
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT"}).Return([]entities.Market{marketBinance, staleKuCoin}, nil)
+
+		resp, err := cfg.market.GetMarkets(ctx, "BTC/USDT", true)
+		require.NoError(t, err)
+		require.Equal(t, []entities.Quote{{Market: marketBinance}}, resp)
+	})
+
+	t.Run("every market is stale, returns error", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT"}).Return([]entities.Market{staleKuCoin}, nil)
+
+		_, err := cfg.market.GetMarkets(ctx, "BTC/USDT", true)
+		require.ErrorIs(t, err, arberrors.ErrMarketNotFound)
+	})
+
+	t.Run("market not found on any exchange, returns error", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT"}).Return(nil, nil)
+
+		_, err := cfg.market.GetMarkets(ctx, "BTC/USDT", false)
+		require.ErrorIs(t, err, arberrors.ErrMarketNotFound)
+	})
+
+	t.Run("fails to get markets, returns error", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT"}).Return(nil, errors.New("connection refused"))
+
+		_, err := cfg.market.GetMarkets(ctx, "BTC/USDT", false)
+		require.EqualError(t, err, "connection refused")
+	})
+}
+
+func TestMarket_GetMarketsForPairs(t *testing.T) {
+	t.Run("gets markets for the given trading pairs", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		markets := []entities.Market{marketBinance, marketKuCoin}
+
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT", "ETH/USDT"}).Return(markets, nil)
+
+		resp, err := cfg.market.GetMarketsForPairs(ctx, []string{"BTC/USDT", "ETH/USDT"}, false)
+		require.NoError(t, err)
+		require.Equal(t, []entities.Quote{{Market: marketBinance}, {Market: marketKuCoin}}, resp)
+	})
+
+	t.Run("defaults to the configured trading pairs", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		market := usecases.NewMarket(usecases.MarketConfig{
+			Store:        cfg.store,
+			TimeNow:      func() time.Time { return testTime },
+			TradingPairs: []string{"BTC/USDT", "ETH/USDT"},
+		})
+
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT", "ETH/USDT"}).Return(nil, nil)
+
+		resp, err := market.GetMarketsForPairs(ctx, nil, false)
+		require.NoError(t, err)
+		require.Empty(t, resp)
+	})
+
+	t.Run("defaults to trading pairs set while running", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		market := usecases.NewMarket(usecases.MarketConfig{
+			Store:        cfg.store,
+			TimeNow:      func() time.Time { return testTime },
+			TradingPairs: []string{"BTC/USDT"},
+		})
+		require.NoError(t, market.SetTradingPairs([]string{"BTC/USDT", "SOL/USDT"}))
+
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT", "SOL/USDT"}).Return(nil, nil)
+
+		resp, err := market.GetMarketsForPairs(ctx, nil, false)
+		require.NoError(t, err)
+		require.Empty(t, resp)
+	})
+
+	t.Run("uses the configured staleness threshold", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		market := usecases.NewMarket(usecases.MarketConfig{
+			StaleAfter: 10 * time.Second,
+			Store:      cfg.store,
+			TimeNow:    func() time.Time { return testTime.Add(30 * time.Second) },
+		})
+
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT"}).Return([]entities.Market{marketBinance}, nil)
+
+		resp, err := market.GetMarketsForPairs(ctx, []string{"BTC/USDT"}, false)
+		require.NoError(t, err)
+		require.Equal(t, []entities.Quote{{Market: marketBinance, Age: 30 * time.Second, Stale: true}}, resp)
+	})
+}
+
+func TestMarket_CheckFreshness(t *testing.T) {
+	newMarket := func(cfg *setupMarketTestConfig, now time.Time) *usecases.Market {
+		return usecases.NewMarket(usecases.MarketConfig{
+			StaleAfter:   time.Minute,
+			Store:        cfg.store,
+			TimeNow:      func() time.Time { return now },
+			TradingPairs: []string{"BTC/USDT", "ETH/USDT"},
+		})
+	}
+
+	t.Run("passes if any market was updated recently", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		older := marketBinance
+		older.TradingPair = "ETH/USDT"
+		older.Timestamp = testTime.Add(-time.Hour)
+
+		cfg.store.EXPECT().GetMarkets(ctx, []entities.Exchange{entities.ExchangeBinance}, []string{"BTC/USDT", "ETH/USDT"}).
+			Return([]entities.Market{older, marketBinance}, nil)
+
+		err := newMarket(cfg, testTime.Add(30*time.Second)).CheckFreshness(ctx, entities.ExchangeBinance)
 		require.NoError(t, err)
-		require.Len(t, resp, 1)
-		require.Equal(t, markets, resp)
 	})
 
-	t.Run("fails to get markets for all exchanges, returns error", func(t *testing.T) {
+	t.Run("fails if every market is stale", func(t *testing.T) {
 		cfg := setupTest(t)
 
-		cfg.store.EXPECT().GetMarket(ctx, entities.ExchangeBinance, "BTC/USDT").Return(entities.Market{}, arberrors.ErrMarketNotFound)
-		cfg.store.EXPECT().GetMarket(ctx, entities.ExchangeKuCoin, "BTC/USDT").Return(entities.Market{}, arberrors.ErrMarketNotFound)
+		cfg.store.EXPECT().GetMarkets(ctx, []entities.Exchange{entities.ExchangeBinance}, []string{"BTC/USDT", "ETH/USDT"}).
+			Return([]entities.Market{marketBinance}, nil)
 
-		_, err := cfg.market.GetMarkets(ctx, "BTC/USDT")
+		err := newMarket(cfg, testTime.Add(2*time.Minute)).CheckFreshness(ctx, entities.ExchangeBinance)
+		require.EqualError(t, err, "latest market on binance is 2m0s old")
+	})
+
+	t.Run("fails if the exchange has no markets", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().GetMarkets(ctx, []entities.Exchange{entities.ExchangeBinance}, []string{"BTC/USDT", "ETH/USDT"}).
+			Return(nil, nil)
+
+		err := newMarket(cfg, testTime).CheckFreshness(ctx, entities.ExchangeBinance)
 		require.ErrorIs(t, err, arberrors.ErrMarketNotFound)
 	})
 }
 
 func TestMarket_UpdateMarket(t *testing.T) {
-	t.Run("updates market successfully when doesn't exist in store", func(t *testing.T) {
+	t.Run("updates market successfully", func(t *testing.T) {
 		cfg := setupTest(t)
 
-		cfg.store.EXPECT().GetMarket(ctx, marketBinance.Exchange, marketBinance.TradingPair).Return(entities.Market{}, arberrors.ErrMarketNotFound)
-		cfg.store.EXPECT().UpdateMarket(ctx, marketBinance).Return(nil)
+		cfg.store.EXPECT().UpdateMarketIfNewer(gomock.Any(), marketBinance).Return(nil)
+		cfg.history.EXPECT().AppendMarket(gomock.Any(), marketBinance).Return(nil)
+		cfg.candles.EXPECT().UpdateCandles(gomock.Any(), newCandles(marketBinance)).Return(nil)
+		cfg.broker.EXPECT().PublishMarket(gomock.Any(), marketBinance).Return(nil)
 
 		err := cfg.market.UpdateMarket(ctx, marketBinance)
 		require.NoError(t, err)
 	})
 
-	t.Run("updates market successfully, old market exists in store", func(t *testing.T) {
+	t.Run("fails to update market, current market has later timestamp than new", func(t *testing.T) {
 		cfg := setupTest(t)
 
-		oldMarket := marketBinance
-		oldMarket.Timestamp = testTime.Add(-time.Second)
+		cfg.store.EXPECT().UpdateMarketIfNewer(gomock.Any(), marketBinance).Return(arberrors.ErrInvalidMarketTimestamp)
 
-		cfg.store.EXPECT().GetMarket(ctx, marketBinance.Exchange, marketBinance.TradingPair).Return(oldMarket, nil)
-		cfg.store.EXPECT().UpdateMarket(ctx, marketBinance).Return(nil)
+		err := cfg.market.UpdateMarket(ctx, marketBinance)
+		require.ErrorIs(t, err, arberrors.ErrInvalidMarketTimestamp)
+	})
+
+	t.Run("updates market successfully, fails to publish", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().UpdateMarketIfNewer(gomock.Any(), marketBinance).Return(nil)
+		cfg.history.EXPECT().AppendMarket(gomock.Any(), marketBinance).Return(nil)
+		cfg.candles.EXPECT().UpdateCandles(gomock.Any(), newCandles(marketBinance)).Return(nil)
+		cfg.broker.EXPECT().PublishMarket(gomock.Any(), marketBinance).Return(errors.New("connection refused"))
 
 		err := cfg.market.UpdateMarket(ctx, marketBinance)
 		require.NoError(t, err)
 	})
 
-	t.Run("fails to update market, current market has later timestamp than new", func(t *testing.T) {
+	t.Run("updates market successfully, fails to append to history", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().UpdateMarketIfNewer(gomock.Any(), marketBinance).Return(nil)
+		cfg.history.EXPECT().AppendMarket(gomock.Any(), marketBinance).Return(errors.New("connection refused"))
+		cfg.candles.EXPECT().UpdateCandles(gomock.Any(), newCandles(marketBinance)).Return(nil)
+		cfg.broker.EXPECT().PublishMarket(gomock.Any(), marketBinance).Return(nil)
+
+		err := cfg.market.UpdateMarket(ctx, marketBinance)
+		require.NoError(t, err)
+	})
+}
+
+func TestMarket_UpdateMarket_Candles(t *testing.T) {
+	t.Run("adds no volume as the 24hr volume rolls off", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		// Trades leaving the 24 hour window lower Volume24hr, which isn't a trade of the pair
+		next := marketBinance
+		next.Timestamp = testTime.Add(time.Second)
+		next.Volume24hr = marketBinance.Volume24hr - 5000
+
+		cfg.store.EXPECT().UpdateMarketIfNewer(gomock.Any(), next).Return(nil)
+		cfg.history.EXPECT().AppendMarket(gomock.Any(), next).Return(nil)
+		cfg.candles.EXPECT().UpdateCandles(gomock.Any(), newCandles(next)).Return(nil)
+		cfg.broker.EXPECT().PublishMarket(gomock.Any(), next).Return(nil)
+
+		require.NoError(t, cfg.market.UpdateMarket(ctx, next))
+	})
+}
+
+func TestMarket_AddTrade(t *testing.T) {
+	trade := entities.Trade{
+		TradingPair: "BTC/USDT",
+		Exchange:    entities.ExchangeBinance,
+		ID:          42,
+		Price:       decimal.NewFromFloat(69700),
+		Quantity:    decimal.NewFromFloat(0.5),
+		Timestamp:   testTime,
+	}
+
+	t.Run("adds the quote asset traded to the candles", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		var candles []entities.Candle
+		for _, interval := range entities.CandleIntervals {
+			c := entities.NewCandle("BTC/USDT", entities.ExchangeBinance, interval, testTime, trade.Price)
+			c.Volume = 34850
+			c.LastTradeID = 42
+			candles = append(candles, c)
+		}
+
+		cfg.candles.EXPECT().UpdateCandles(gomock.Any(), candles).Return(nil)
+
+		require.NoError(t, cfg.market.AddTrade(ctx, trade))
+	})
+
+	t.Run("fails to update candles", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.candles.EXPECT().UpdateCandles(gomock.Any(), gomock.Any()).Return(errors.New("connection refused"))
+
+		require.Error(t, cfg.market.AddTrade(ctx, trade))
+	})
+
+	t.Run("does nothing without candles", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		m := usecases.NewMarket(usecases.MarketConfig{Store: cfg.store, TimeNow: func() time.Time { return testTime }})
+
+		require.NoError(t, m.AddTrade(ctx, trade))
+	})
+}
+
+func TestMarket_GetCandles(t *testing.T) {
+	from := testTime.Add(-time.Hour)
+
+	t.Run("gets candles successfully", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		candles := newCandles(marketBinance)[1:2]
+
+		cfg.candles.EXPECT().GetCandles(ctx, entities.ExchangeBinance, "BTC/USDT", entities.CandleInterval1m, from, testTime).Return(candles, nil)
+
+		resp, err := cfg.market.GetCandles(ctx, "BTC/USDT", entities.ExchangeBinance, entities.CandleInterval1m, from, testTime)
+		require.NoError(t, err)
+		require.Equal(t, candles, resp)
+	})
+
+	t.Run("fails to get candles, unknown interval", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		_, err := cfg.market.GetCandles(ctx, "BTC/USDT", entities.ExchangeBinance, "3m", from, testTime)
+		require.ErrorIs(t, err, arberrors.ErrInvalidCandleInterval)
+	})
+
+	t.Run("fails to get candles, unknown exchange", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		_, err := cfg.market.GetCandles(ctx, "BTC/USDT", "mtgox", entities.CandleInterval1m, from, testTime)
+		require.ErrorIs(t, err, arberrors.ErrExchangeNotFound)
+	})
+
+	t.Run("fails to get candles, no candle store", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		market := usecases.NewMarket(usecases.MarketConfig{
+			Store:   cfg.store,
+			TimeNow: func() time.Time { return testTime },
+		})
+
+		_, err := market.GetCandles(ctx, "BTC/USDT", entities.ExchangeBinance, entities.CandleInterval1m, from, testTime)
+		require.ErrorIs(t, err, arberrors.ErrCandlesUnavailable)
+	})
+}
+
+func TestMarket_GetMarketHistory(t *testing.T) {
+	from := testTime.Add(-time.Hour)
+
+	t.Run("gets market history successfully", func(t *testing.T) {
 		cfg := setupTest(t)
 
 		oldMarket := marketBinance
-		oldMarket.Timestamp = testTime.Add(time.Second)
+		oldMarket.Timestamp = testTime.Add(-time.Minute)
+		markets := []entities.Market{oldMarket, marketBinance}
 
-		cfg.store.EXPECT().GetMarket(ctx, marketBinance.Exchange, marketBinance.TradingPair).Return(oldMarket, nil)
+		cfg.history.EXPECT().GetMarketHistory(ctx, entities.ExchangeBinance, "BTC/USDT", from, testTime, 100).Return(markets, nil)
 
-		err := cfg.market.UpdateMarket(ctx, marketBinance)
-		require.Error(t, err)
+		resp, err := cfg.market.GetMarketHistory(ctx, "BTC/USDT", entities.ExchangeBinance, from, testTime, 100)
+		require.NoError(t, err)
+		require.Equal(t, markets, resp)
+	})
+
+	t.Run("caps the limit at the max", func(t *testing.T) {
+		for _, limit := range []int{0, 10001} {
+			cfg := setupTest(t)
+
+			cfg.history.EXPECT().GetMarketHistory(ctx, entities.ExchangeBinance, "BTC/USDT", from, testTime, 10000).Return(nil, nil)
+
+			_, err := cfg.market.GetMarketHistory(ctx, "BTC/USDT", entities.ExchangeBinance, from, testTime, limit)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("fails to get market history, unknown exchange", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		_, err := cfg.market.GetMarketHistory(ctx, "BTC/USDT", "mtgox", from, testTime, 0)
+		require.ErrorIs(t, err, arberrors.ErrExchangeNotFound)
+	})
+
+	t.Run("fails to get market history, from is after to", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		_, err := cfg.market.GetMarketHistory(ctx, "BTC/USDT", entities.ExchangeBinance, testTime, from, 0)
+		require.ErrorIs(t, err, arberrors.ErrInvalidTimeRange)
+	})
+
+	t.Run("fails to get market history, no history", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		market := usecases.NewMarket(usecases.MarketConfig{
+			Store:   cfg.store,
+			TimeNow: func() time.Time { return testTime },
+		})
+
+		_, err := market.GetMarketHistory(ctx, "BTC/USDT", entities.ExchangeBinance, from, testTime, 0)
+		require.ErrorIs(t, err, arberrors.ErrHistoryUnavailable)
+	})
+}
+
+func TestMarket_StreamMarkets(t *testing.T) {
+	t.Run("streams markets successfully", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		markets := make(chan entities.Market, 1)
+		markets <- marketBinance
+		close(markets)
+
+		cfg.broker.EXPECT().SubscribeMarkets(ctx, []entities.Exchange{entities.ExchangeBinance}, []string{"BTC/USDT"}).Return(markets, nil)
+
+		resp, err := cfg.market.StreamMarkets(ctx, []string{"BTC/USDT"}, []entities.Exchange{entities.ExchangeBinance})
+		require.NoError(t, err)
+		require.Equal(t, marketBinance, <-resp)
+	})
+
+	t.Run("fails to stream markets, unknown exchange", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		_, err := cfg.market.StreamMarkets(ctx, nil, []entities.Exchange{"mtgox"})
+		require.ErrorIs(t, err, arberrors.ErrExchangeNotFound)
+	})
+
+	t.Run("fails to stream markets, no broker", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		market := usecases.NewMarket(usecases.MarketConfig{
+			Store:   cfg.store,
+			TimeNow: func() time.Time { return testTime },
+		})
+
+		_, err := market.StreamMarkets(ctx, nil, nil)
+		require.ErrorIs(t, err, arberrors.ErrStreamingUnavailable)
+	})
+}
+
+func TestMarket_GetTopSpreads(t *testing.T) {
+	btcBinance := entities.Market{
+		Exchange:      entities.ExchangeBinance,
+		TradingPair:   "BTC/USDT",
+		BestBuyPrice:  decimal.NewFromFloat(69000),
+		BestSellPrice: decimal.NewFromFloat(69010),
+		Timestamp:     testTime.Add(-time.Second),
+	}
+	btcKuCoin := entities.Market{
+		Exchange:      entities.ExchangeKuCoin,
+		TradingPair:   "BTC/USDT",
+		BestBuyPrice:  decimal.NewFromFloat(69100),
+		BestSellPrice: decimal.NewFromFloat(69110),
+		Timestamp:     testTime.Add(-2 * time.Second),
+	}
+	ethBinance := entities.Market{
+		Exchange:      entities.ExchangeBinance,
+		TradingPair:   "ETH/USDT",
+		BestBuyPrice:  decimal.NewFromFloat(2600),
+		BestSellPrice: decimal.NewFromFloat(2601),
+		Timestamp:     testTime,
+	}
+	ethKuCoin := entities.Market{
+		Exchange:      entities.ExchangeKuCoin,
+		TradingPair:   "ETH/USDT",
+		BestBuyPrice:  decimal.NewFromFloat(2599),
+		BestSellPrice: decimal.NewFromFloat(2600),
+		Timestamp:     testTime,
+	}
+
+	t.Run("gets spreads ordered by spread percent", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"ETH/USDT", "BTC/USDT"}).
+			Return([]entities.Market{ethBinance, ethKuCoin, btcBinance, btcKuCoin}, nil)
+
+		resp, err := cfg.market.GetTopSpreads(ctx, []string{"ETH/USDT", "BTC/USDT"}, 0)
+		require.NoError(t, err)
+		require.Len(t, resp, 2)
+
+		// Buy BTC on Binance at 69010, sell on KuCoin at 69100
+		require.Equal(t, "BTC/USDT", resp[0].TradingPair)
+		require.Equal(t, entities.ExchangeBinance, resp[0].BuyExchange)
+		require.Equal(t, entities.ExchangeKuCoin, resp[0].SellExchange)
+		require.True(t, decimal.NewFromFloat(90).Equal(resp[0].Spread))
+		require.Equal(t, time.Second, resp[0].BuyQuoteAge)
+		require.Equal(t, 2*time.Second, resp[0].SellQuoteAge)
+
+		// Buy ETH on KuCoin at 2600, sell on Binance at 2600
+		require.Equal(t, "ETH/USDT", resp[1].TradingPair)
+		require.Equal(t, entities.ExchangeKuCoin, resp[1].BuyExchange)
+		require.Equal(t, entities.ExchangeBinance, resp[1].SellExchange)
+		require.True(t, resp[1].Spread.IsZero())
+	})
+
+	t.Run("limits number of spreads", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"ETH/USDT", "BTC/USDT"}).
+			Return([]entities.Market{ethBinance, ethKuCoin, btcBinance, btcKuCoin}, nil)
+
+		resp, err := cfg.market.GetTopSpreads(ctx, []string{"ETH/USDT", "BTC/USDT"}, 1)
+		require.NoError(t, err)
+		require.Len(t, resp, 1)
+		require.Equal(t, "BTC/USDT", resp[0].TradingPair)
+	})
+
+	t.Run("ranks spreads after fees", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		market := usecases.NewMarket(usecases.MarketConfig{
+			Fees: entities.FeeSchedule{
+				entities.ExchangeBinance: {Tiers: map[int]entities.Fee{0: {Taker: decimal.NewFromFloat(0.001)}}},
+				entities.ExchangeKuCoin:  {Tiers: map[int]entities.Fee{0: {Taker: decimal.NewFromFloat(0.001)}}},
+			},
+			Store:   cfg.store,
+			TimeNow: func() time.Time { return testTime },
+		})
+
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT"}).Return([]entities.Market{btcBinance, btcKuCoin}, nil)
+
+		resp, err := market.GetTopSpreads(ctx, []string{"BTC/USDT"}, 0)
+		require.NoError(t, err)
+		require.Len(t, resp, 1)
+
+		// Buy costs 69010 * 1.001 = 69079.01, sell returns 69100 * 0.999 = 69030.9
+		require.True(t, decimal.NewFromFloat(90).Equal(resp[0].Spread))
+		require.True(t, decimal.NewFromFloat(-48.11).Equal(resp[0].NetSpread))
+		require.True(t, resp[0].NetSpreadPercent.IsNegative())
+	})
+
+	t.Run("skips stale quotes", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		staleKuCoin := btcKuCoin
+		staleKuCoin.Timestamp = testTime.Add(-8 * time.Minute)
+
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT"}).Return([]entities.Market{btcBinance, staleKuCoin}, nil)
+
+		resp, err := cfg.market.GetTopSpreads(ctx, []string{"BTC/USDT"}, 0)
+		require.NoError(t, err)
+		require.Empty(t, resp)
+	})
+
+	t.Run("skips pairs not found on two exchanges", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().GetMarkets(ctx, entities.Exchanges, []string{"BTC/USDT", "XMR/USDT"}).Return([]entities.Market{btcBinance}, nil)
+
+		resp, err := cfg.market.GetTopSpreads(ctx, []string{"BTC/USDT", "XMR/USDT"}, 0)
+		require.NoError(t, err)
+		require.Empty(t, resp)
+	})
+}
+
+func TestMarket_GetOrderBooks(t *testing.T) {
+	orderBookBinance := entities.OrderBook{
+		TradingPair: "BTC/USDT",
+		Exchange:    entities.ExchangeBinance,
+		Bids:        []entities.OrderBookLevel{{Price: decimal.NewFromFloat(69000), Quantity: decimal.NewFromFloat(1.5)}},
+		Asks:        []entities.OrderBookLevel{{Price: decimal.NewFromFloat(69010), Quantity: decimal.NewFromFloat(0.5)}},
+		Sequence:    100,
+		Timestamp:   testTime,
+	}
+
+	t.Run("gets order books successfully", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeBinance, "BTC/USDT").Return(orderBookBinance, nil)
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeKuCoin, "BTC/USDT").Return(entities.OrderBook{}, arberrors.ErrOrderBookNotFound)
+		cfg.expectOrderBookNotFoundOnOtherExchanges("BTC/USDT", entities.ExchangeBinance, entities.ExchangeKuCoin)
+
+		resp, err := cfg.market.GetOrderBooks(ctx, "BTC/USDT", nil)
+		require.NoError(t, err)
+		require.Equal(t, []entities.OrderBook{orderBookBinance}, resp)
+	})
+
+	t.Run("gets order book for one exchange", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeBinance, "BTC/USDT").Return(orderBookBinance, nil)
+
+		resp, err := cfg.market.GetOrderBooks(ctx, "BTC/USDT", []entities.Exchange{entities.ExchangeBinance})
+		require.NoError(t, err)
+		require.Equal(t, []entities.OrderBook{orderBookBinance}, resp)
+	})
+
+	t.Run("fails to get order books for all exchanges, returns error", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeBinance, "BTC/USDT").Return(entities.OrderBook{}, arberrors.ErrOrderBookNotFound)
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeKuCoin, "BTC/USDT").Return(entities.OrderBook{}, arberrors.ErrOrderBookNotFound)
+		cfg.expectOrderBookNotFoundOnOtherExchanges("BTC/USDT", entities.ExchangeBinance, entities.ExchangeKuCoin)
+
+		_, err := cfg.market.GetOrderBooks(ctx, "BTC/USDT", nil)
+		require.ErrorIs(t, err, arberrors.ErrOrderBookNotFound)
+	})
+
+	t.Run("fails to get order books, unknown exchange", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		_, err := cfg.market.GetOrderBooks(ctx, "BTC/USDT", []entities.Exchange{"mtgox"})
+		require.ErrorIs(t, err, arberrors.ErrExchangeNotFound)
+	})
+}
+
+func TestMarket_UpdateOrderBook(t *testing.T) {
+	orderBook := entities.OrderBook{
+		TradingPair: "BTC/USDT",
+		Exchange:    entities.ExchangeKuCoin,
+		Sequence:    100,
+		Timestamp:   testTime,
+	}
+
+	t.Run("updates order book successfully", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().UpdateOrderBookIfNewer(ctx, orderBook).Return(nil)
+
+		err := cfg.market.UpdateOrderBook(ctx, orderBook)
+		require.NoError(t, err)
+	})
+
+	t.Run("fails to update order book, current order book has later timestamp than new", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().UpdateOrderBookIfNewer(ctx, orderBook).Return(arberrors.ErrInvalidMarketTimestamp)
+
+		err := cfg.market.UpdateOrderBook(ctx, orderBook)
+		require.ErrorIs(t, err, arberrors.ErrInvalidMarketTimestamp)
+	})
+}
+
+func TestMarket_GetExecutableArbitrage(t *testing.T) {
+	orderBookBinance := entities.OrderBook{
+		TradingPair: "BTC/USDT",
+		Exchange:    entities.ExchangeBinance,
+		Bids:        []entities.OrderBookLevel{{Price: decimal.NewFromFloat(99), Quantity: decimal.NewFromFloat(5)}},
+		Asks: []entities.OrderBookLevel{
+			{Price: decimal.NewFromFloat(100), Quantity: decimal.NewFromFloat(1)},
+			{Price: decimal.NewFromFloat(101), Quantity: decimal.NewFromFloat(2)},
+			{Price: decimal.NewFromFloat(103), Quantity: decimal.NewFromFloat(5)},
+		},
+	}
+	orderBookKuCoin := entities.OrderBook{
+		TradingPair: "BTC/USDT",
+		Exchange:    entities.ExchangeKuCoin,
+		Bids: []entities.OrderBookLevel{
+			{Price: decimal.NewFromFloat(102), Quantity: decimal.NewFromFloat(1.5)},
+			{Price: decimal.NewFromFloat(101.5), Quantity: decimal.NewFromFloat(1)},
+			{Price: decimal.NewFromFloat(100), Quantity: decimal.NewFromFloat(5)},
+		},
+		Asks: []entities.OrderBookLevel{{Price: decimal.NewFromFloat(104), Quantity: decimal.NewFromFloat(5)}},
+	}
+
+	t.Run("sizes arbitrage across levels", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeBinance, "BTC/USDT").Return(orderBookBinance, nil)
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeKuCoin, "BTC/USDT").Return(orderBookKuCoin, nil)
+		cfg.expectOrderBookNotFoundOnOtherExchanges("BTC/USDT", entities.ExchangeBinance, entities.ExchangeKuCoin)
+
+		resp, err := cfg.market.GetExecutableArbitrage(ctx, "BTC/USDT", decimal.Zero)
+		require.NoError(t, err)
+		require.Len(t, resp, 1)
+
+		// Buys 1 @ 100 and 0.5 @ 101 to sell 1.5 @ 102, then 1 @ 101 to sell 1 @ 101.5
+		arb := resp[0]
+		require.Equal(t, entities.ExchangeBinance, arb.BuyExchange)
+		require.Equal(t, entities.ExchangeKuCoin, arb.SellExchange)
+		require.True(t, decimal.NewFromFloat(2.5).Equal(arb.Quantity))
+		require.True(t, decimal.NewFromFloat(251.5).Equal(arb.BuyNotional))
+		require.True(t, decimal.NewFromFloat(254.5).Equal(arb.SellNotional))
+		require.True(t, decimal.NewFromFloat(100.6).Equal(arb.BuyVWAP))
+		require.True(t, decimal.NewFromFloat(101.8).Equal(arb.SellVWAP))
+		require.True(t, decimal.NewFromFloat(3).Equal(arb.Profit))
+	})
+
+	t.Run("limits arbitrage to max notional", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeBinance, "BTC/USDT").Return(orderBookBinance, nil)
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeKuCoin, "BTC/USDT").Return(orderBookKuCoin, nil)
+		cfg.expectOrderBookNotFoundOnOtherExchanges("BTC/USDT", entities.ExchangeBinance, entities.ExchangeKuCoin)
+
+		resp, err := cfg.market.GetExecutableArbitrage(ctx, "BTC/USDT", decimal.NewFromFloat(150.5))
+		require.NoError(t, err)
+		require.Len(t, resp, 1)
+
+		// Buys 1 @ 100 and 0.5 @ 101
+		require.True(t, decimal.NewFromFloat(1.5).Equal(resp[0].Quantity))
+		require.True(t, decimal.NewFromFloat(150.5).Equal(resp[0].BuyNotional))
+		require.True(t, decimal.NewFromFloat(2.5).Equal(resp[0].Profit))
+	})
+
+	t.Run("no arbitrage once fees are paid", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		market := usecases.NewMarket(usecases.MarketConfig{
+			Fees: entities.FeeSchedule{
+				entities.ExchangeBinance: {Tiers: map[int]entities.Fee{0: {Taker: decimal.NewFromFloat(0.01)}}},
+				entities.ExchangeKuCoin:  {Tiers: map[int]entities.Fee{0: {Taker: decimal.NewFromFloat(0.01)}}},
+			},
+			Store:   cfg.store,
+			TimeNow: func() time.Time { return testTime },
+		})
+
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeBinance, "BTC/USDT").Return(orderBookBinance, nil)
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeKuCoin, "BTC/USDT").Return(orderBookKuCoin, nil)
+		cfg.expectOrderBookNotFoundOnOtherExchanges("BTC/USDT", entities.ExchangeBinance, entities.ExchangeKuCoin)
+
+		resp, err := market.GetExecutableArbitrage(ctx, "BTC/USDT", decimal.Zero)
+		require.NoError(t, err)
+		require.Empty(t, resp)
+	})
+
+	btcBinance := entities.Instrument{
+		Exchange:    entities.ExchangeBinance,
+		TradingPair: "BTC/USDT",
+		Status:      entities.ListingStatusTrading,
+		TickSize:    decimal.RequireFromString("0.01"),
+		StepSize:    decimal.RequireFromString("1"),
+	}
+	btcKuCoin := entities.Instrument{
+		Exchange:    entities.ExchangeKuCoin,
+		TradingPair: "BTC/USDT",
+		Status:      entities.ListingStatusTrading,
+		TickSize:    decimal.RequireFromString("0.1"),
+		StepSize:    decimal.RequireFromString("0.1"),
+	}
+
+	// withInstruments returns a market that rounds arbitrage to the given instruments.
+	withInstruments := func(t *testing.T, cfg *setupMarketTestConfig, instruments ...entities.Instrument) *usecases.Market {
+		var sources []usecases.InstrumentSource
+		for _, instrument := range instruments {
+			source := mocks.NewMockInstrumentSource(cfg.mockCtrl)
+			source.EXPECT().Exchange().Return(instrument.Exchange).AnyTimes()
+			source.EXPECT().GetInstruments(ctx).Return([]entities.Instrument{instrument}, nil)
+			sources = append(sources, source)
+		}
+
+		i := usecases.NewInstruments(usecases.InstrumentsConfig{Sources: sources})
+		require.NoError(t, i.Refresh(ctx))
+
+		return usecases.NewMarket(usecases.MarketConfig{
+			Instruments: i,
+			Store:       cfg.store,
+			TimeNow:     func() time.Time { return testTime },
+		})
+	}
+
+	t.Run("rounds arbitrage to order constraints", func(t *testing.T) {
+		cfg := setupTest(t)
+		market := withInstruments(t, cfg, btcBinance, btcKuCoin)
+
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeBinance, "BTC/USDT").Return(orderBookBinance, nil)
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeKuCoin, "BTC/USDT").Return(orderBookKuCoin, nil)
+		cfg.expectOrderBookNotFoundOnOtherExchanges("BTC/USDT", entities.ExchangeBinance, entities.ExchangeKuCoin)
+
+		resp, err := market.GetExecutableArbitrage(ctx, "BTC/USDT", decimal.Zero)
+		require.NoError(t, err)
+		require.Len(t, resp, 1)
+
+		// 2.5 rounded down to Binance's step of 1, so buys 1 @ 100 and 1 @ 101 to sell 1.5 @ 102 and 0.5 @ 101.5
+		arb := resp[0]
+		require.True(t, decimal.NewFromFloat(2).Equal(arb.Quantity))
+		require.True(t, decimal.NewFromFloat(201).Equal(arb.BuyNotional))
+		require.True(t, decimal.NewFromFloat(203.75).Equal(arb.SellNotional))
+		require.True(t, decimal.NewFromFloat(100.5).Equal(arb.BuyVWAP))
+		require.True(t, decimal.NewFromFloat(101.875).Equal(arb.SellVWAP))
+		require.True(t, decimal.NewFromFloat(2.75).Equal(arb.Profit))
+	})
+
+	t.Run("rounds arbitrage to a multiple of both step sizes", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		binance := btcBinance
+		binance.StepSize = decimal.RequireFromString("0.75")
+		market := withInstruments(t, cfg, binance, btcKuCoin)
+
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeBinance, "BTC/USDT").Return(orderBookBinance, nil)
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeKuCoin, "BTC/USDT").Return(orderBookKuCoin, nil)
+		cfg.expectOrderBookNotFoundOnOtherExchanges("BTC/USDT", entities.ExchangeBinance, entities.ExchangeKuCoin)
+
+		resp, err := market.GetExecutableArbitrage(ctx, "BTC/USDT", decimal.Zero)
+		require.NoError(t, err)
+		require.Len(t, resp, 1)
+
+		// 2.5 rounded down to 1.5, a multiple of 0.75 and 0.1, so buys 1 @ 100 and 0.5 @ 101 to sell 1.5 @ 102
+		require.True(t, decimal.NewFromFloat(1.5).Equal(resp[0].Quantity))
+		require.True(t, decimal.NewFromFloat(150.5).Equal(resp[0].BuyNotional))
+		require.True(t, decimal.NewFromFloat(2.5).Equal(resp[0].Profit))
+	})
+
+	t.Run("limits arbitrage to max quantity", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		kucoin := btcKuCoin
+		kucoin.MaxQuantity = decimal.NewFromFloat(1.25)
+		binance := btcBinance
+		binance.StepSize = decimal.RequireFromString("0.1")
+		market := withInstruments(t, cfg, binance, kucoin)
+
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeBinance, "BTC/USDT").Return(orderBookBinance, nil)
+		cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeKuCoin, "BTC/USDT").Return(orderBookKuCoin, nil)
+		cfg.expectOrderBookNotFoundOnOtherExchanges("BTC/USDT", entities.ExchangeBinance, entities.ExchangeKuCoin)
+
+		resp, err := market.GetExecutableArbitrage(ctx, "BTC/USDT", decimal.Zero)
+		require.NoError(t, err)
+		require.Len(t, resp, 1)
+
+		// Capped at 1.25 then rounded down to 1.2, so buys 1 @ 100 and 0.2 @ 101 to sell 1.2 @ 102
+		require.True(t, decimal.NewFromFloat(1.2).Equal(resp[0].Quantity))
+		require.True(t, decimal.NewFromFloat(120.2).Equal(resp[0].BuyNotional))
+		require.True(t, decimal.NewFromFloat(2.2).Equal(resp[0].Profit))
+	})
+
+	t.Run("leaves out arbitrage an exchange wouldn't accept", func(t *testing.T) {
+		smallNotional := btcKuCoin
+		smallNotional.MinNotional = decimal.NewFromFloat(250)
+		smallQuantity := btcBinance
+		smallQuantity.MinQuantity = decimal.NewFromFloat(3)
+		halted := btcKuCoin
+		halted.Status = entities.ListingStatusHalted
+
+		tests := map[string][]entities.Instrument{
+			"below min notional":   {btcBinance, smallNotional},
+			"below min quantity":   {smallQuantity, btcKuCoin},
+			"not trading":          {btcBinance, halted},
+			"instrument not known": {btcBinance},
+		}
+
+		for name, instruments := range tests {
+			t.Run(name, func(t *testing.T) {
+				cfg := setupTest(t)
+				market := withInstruments(t, cfg, instruments...)
+
+				cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeBinance, "BTC/USDT").Return(orderBookBinance, nil)
+				cfg.store.EXPECT().GetOrderBook(ctx, entities.ExchangeKuCoin, "BTC/USDT").Return(orderBookKuCoin, nil)
+				cfg.expectOrderBookNotFoundOnOtherExchanges("BTC/USDT", entities.ExchangeBinance, entities.ExchangeKuCoin)
+
+				resp, err := market.GetExecutableArbitrage(ctx, "BTC/USDT", decimal.Zero)
+				require.NoError(t, err)
+				require.Empty(t, resp)
+			})
+		}
 	})
 }
@@ -9,7 +9,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/gorilla/websocket"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
 	"github.com/rs/zerolog/log"
 )
 
@@ -18,43 +18,54 @@ const (
 	APIKeyHeader   = "X-MBX-APIKEY"
 )
 
-// init initializes WebSocketClient by creating a listenKey, establishing a WebSocket connection,
-// and subscribing to the desired trading pairs.
-func (c *WebsocketClient) init(ctx context.Context) error {
-	listenKey, err := c.getListenKey(ctx)
+// Bootstrap creates a listenKey, which is kept alive for as long as the connection lasts.
+func (e *exchange) Bootstrap(ctx context.Context) (connector.Connection, error) {
+	listenKey, err := e.getListenKey(ctx)
 	if err != nil {
-		return err
+		return connector.Connection{}, err
 	}
-	c.listenKey = listenKey
 
-	go c.keepAliveListenKey(ctx)
+	go e.keepAliveListenKey(ctx, listenKey)
 
-	ws, resp, err := websocket.DefaultDialer.Dial(c.websocketURL+c.listenKey, nil)
-	if err != nil {
-		return fmt.Errorf("failed to dial websocket: %w", err)
-	}
-	resp.Body.Close()
+	return connector.Connection{URL: e.websocketURL + listenKey}, nil
+}
 
-	c.ws = ws
+// SubscribeMessages returns a message subscribing to the ticker, depth and trade streams of each symbol.
+func (e *exchange) SubscribeMessages(symbols []string) ([][]byte, error) {
+	return subscriptionMessages("SUBSCRIBE", symbols)
+}
 
-	return c.subscribe()
+// UnsubscribeMessages returns a message unsubscribing from the ticker, depth and trade streams of each symbol.
+func (e *exchange) UnsubscribeMessages(symbols []string) ([][]byte, error) {
+	return subscriptionMessages("UNSUBSCRIBE", symbols)
 }
 
-// subscribe subscribes to trading pairs via WebSocket.
-func (c *WebsocketClient) subscribe() error {
+func subscriptionMessages(method string, symbols []string) ([][]byte, error) {
 	var params []string
-	for pair := range c.binanceSymbolToPair {
+	for _, symbol := range symbols {
 		// Binance needs the pair formatted in lower case (e.g: btcbusd@ticker)
-		params = append(params, fmt.Sprintf("%s@ticker", strings.ToLower(pair)))
+		params = append(params,
+			fmt.Sprintf("%s@ticker", strings.ToLower(symbol)),
+			fmt.Sprintf("%s@depth@100ms", strings.ToLower(symbol)),
+			fmt.Sprintf("%s@aggTrade", strings.ToLower(symbol)),
+		)
 	}
 
-	err := c.ws.WriteJSON(&subscriptionRequest{
-		Method: "SUBSCRIBE",
+	msg, err := json.Marshal(&subscriptionRequest{
+		Method: method,
 		Params: params,
 		ID:     1,
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{msg}, nil
+}
 
-	return err
+// KeepaliveMessage isn't needed, Binance keeps the connection alive with ping frames.
+func (e *exchange) KeepaliveMessage() ([]byte, error) {
+	return nil, nil
 }
 
 type subscriptionRequest struct {
@@ -64,15 +75,15 @@ type subscriptionRequest struct {
 }
 
 // getListenKey creates and retrieves a listenKey from Binance.
-func (c *WebsocketClient) getListenKey(ctx context.Context) (string, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.hostname+ListenKeyRoute, nil)
+func (e *exchange) getListenKey(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.hostname+ListenKeyRoute, nil)
 	if err != nil {
 		return "", err
 	}
 
-	req.Header.Set("X-MBX-APIKEY", c.apiKey)
+	req.Header.Set("X-MBX-APIKEY", e.apiKey)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := e.httpClient.Do(req)
 	if err != nil {
 		return "", err
 	}
@@ -103,8 +114,8 @@ type createListenKeyResponse struct {
 // keepAliveListenKey sends a PUT request to Binance to keep the listenKey alive.
 // Binance requires periodic pings (every 60m) to keep the listenKey from expiring.
 // If the request is not successful, returns an error.
-func (c *WebsocketClient) keepAliveListenKey(ctx context.Context) {
-	pt := time.NewTicker(c.pingInterval)
+func (e *exchange) keepAliveListenKey(ctx context.Context, listenKey string) {
+	pt := time.NewTicker(e.pingInterval)
 	defer pt.Stop()
 
 	for {
@@ -113,7 +124,7 @@ func (c *WebsocketClient) keepAliveListenKey(ctx context.Context) {
 			log.Info().Msg("Context canceled, stopping ping")
 			return
 		case <-pt.C:
-			if err := c.pingListenKey(ctx); err != nil {
+			if err := e.pingListenKey(ctx, listenKey); err != nil {
 				log.Err(err).Msg("Failed to ping Binance listenKey")
 			}
 		}
@@ -121,18 +132,18 @@ func (c *WebsocketClient) keepAliveListenKey(ctx context.Context) {
 }
 
 // pingListenKeyRequest refreshes the Binance listenKey by sending a PUT request.
-func (c *WebsocketClient) pingListenKey(ctx context.Context) error {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.hostname+ListenKeyRoute, nil)
+func (e *exchange) pingListenKey(ctx context.Context, listenKey string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, e.hostname+ListenKeyRoute, nil)
 	if err != nil {
 		return fmt.Errorf("error creating ping request: %w", err)
 	}
 
-	req.Header.Set(APIKeyHeader, c.apiKey)
+	req.Header.Set(APIKeyHeader, e.apiKey)
 	q := req.URL.Query()
-	q.Add("listenKey", c.listenKey)
+	q.Add("listenKey", listenKey)
 	req.URL.RawQuery = q.Encode()
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := e.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("error sending ping request: %w", err)
 	}
@@ -0,0 +1,184 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ExchangeInfoRoute = "/api/v3/exchangeInfo"
+	Ticker24hrRoute   = "/api/v3/ticker/24hr"
+)
+
+type MetadataConfig struct {
+	Hostname   string
+	HTTPClient http.Client
+}
+
+// Metadata gets what's listed on Binance from its public REST API.
+type Metadata struct {
+	hostname   string
+	httpClient http.Client
+}
+
+func NewMetadata(cfg MetadataConfig) *Metadata {
+	return &Metadata{
+		hostname:   cfg.Hostname,
+		httpClient: cfg.HTTPClient,
+	}
+}
+
+func (m *Metadata) Exchange() entities.Exchange {
+	return entities.ExchangeBinance
+}
+
+// GetListings returns every spot symbol on Binance, with its 24hr volume in the quote asset.
+func (m *Metadata) GetListings(ctx context.Context) ([]entities.Listing, error) {
+	var info exchangeInfoResponse
+	if err := m.get(ctx, ExchangeInfoRoute, &info); err != nil {
+		return nil, fmt.Errorf("failed to get exchange info: %w", err)
+	}
+
+	var tickers []ticker24hr
+	if err := m.get(ctx, Ticker24hrRoute, &tickers); err != nil {
+		return nil, fmt.Errorf("failed to get 24hr tickers: %w", err)
+	}
+
+	volumes := make(map[string]float64, len(tickers))
+	for _, t := range tickers {
+		v, err := strconv.ParseFloat(t.QuoteVolume, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse volume of %s: %w", t.Symbol, err)
+		}
+		volumes[t.Symbol] = v
+	}
+
+	listings := make([]entities.Listing, 0, len(info.Symbols))
+	for _, s := range info.Symbols {
+		pair, err := entities.NewPair(s.BaseAsset, s.QuoteAsset)
+		if err != nil {
+			log.Debug().Err(err).Str("symbol", s.Symbol).Msg("Skipping symbol")
+			continue
+		}
+
+		listings = append(listings, entities.Listing{
+			Exchange:    entities.ExchangeBinance,
+			TradingPair: pair.String(),
+			Base:        pair.Base.String(),
+			Quote:       pair.Quote.String(),
+			Status:      s.status(),
+			Volume24hr:  volumes[s.Symbol],
+		})
+	}
+
+	return listings, nil
+}
+
+// GetInstruments returns the order constraints of every spot symbol on Binance, from the filters in its exchange info.
+func (m *Metadata) GetInstruments(ctx context.Context) ([]entities.Instrument, error) {
+	var info exchangeInfoResponse
+	if err := m.get(ctx, ExchangeInfoRoute, &info); err != nil {
+		return nil, fmt.Errorf("failed to get exchange info: %w", err)
+	}
+
+	instruments := make([]entities.Instrument, 0, len(info.Symbols))
+	for _, s := range info.Symbols {
+		pair, err := entities.NewPair(s.BaseAsset, s.QuoteAsset)
+		if err != nil {
+			log.Debug().Err(err).Str("symbol", s.Symbol).Msg("Skipping symbol")
+			continue
+		}
+
+		instrument := entities.Instrument{
+			Exchange:    entities.ExchangeBinance,
+			TradingPair: pair.String(),
+			Status:      s.status(),
+		}
+
+		for _, f := range s.Filters {
+			switch f.FilterType {
+			case "PRICE_FILTER":
+				instrument.TickSize = f.TickSize
+			case "LOT_SIZE":
+				instrument.StepSize = f.StepSize
+				instrument.MinQuantity = f.MinQty
+				instrument.MaxQuantity = f.MaxQty
+			case "NOTIONAL", "MIN_NOTIONAL":
+				instrument.MinNotional = f.MinNotional
+			}
+		}
+
+		instruments = append(instruments, instrument)
+	}
+
+	return instruments, nil
+}
+
+// get decodes the JSON response of a GET request to a route.
+func (m *Metadata) get(ctx context.Context, route string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.hostname+route, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-ok status: %s", resp.Status)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// exchangeInfoResponse represents the JSON returned by Binance's exchangeInfo endpoint.
+type exchangeInfoResponse struct {
+	Symbols []symbolInfo `json:"symbols"`
+}
+
+type symbolInfo struct {
+	Symbol     string         `json:"symbol"`
+	Status     string         `json:"status"` // e.g. TRADING, BREAK or HALT
+	BaseAsset  string         `json:"baseAsset"`
+	QuoteAsset string         `json:"quoteAsset"`
+	Filters    []symbolFilter `json:"filters"`
+}
+
+func (s symbolInfo) status() entities.ListingStatus {
+	if s.Status == "TRADING" {
+		return entities.ListingStatusTrading
+	}
+
+	return entities.ListingStatusHalted
+}
+
+// symbolFilter is one of a symbol's trading rules. Only the fields of its filter type are set.
+type symbolFilter struct {
+	FilterType  string          `json:"filterType"` // e.g. PRICE_FILTER, LOT_SIZE or NOTIONAL
+	TickSize    decimal.Decimal `json:"tickSize"`
+	StepSize    decimal.Decimal `json:"stepSize"`
+	MinQty      decimal.Decimal `json:"minQty"`
+	MaxQty      decimal.Decimal `json:"maxQty"`
+	MinNotional decimal.Decimal `json:"minNotional"`
+}
+
+type ticker24hr struct {
+	Symbol      string `json:"symbol"`
+	QuoteVolume string `json:"quoteVolume"`
+}
@@ -0,0 +1,160 @@
+package binance
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	DepthRoute = "/api/v3/depth"
+
+	// depthSnapshotLimit is the number of levels requested in an order book snapshot.
+	depthSnapshotLimit = 1000
+)
+
+// decodeDepthUpdate decodes a "depthUpdate" message. Binance's update IDs are used as the order book sequence,
+// following its guide to managing a local order book.
+func decodeDepthUpdate(p []byte) (connector.OrderBookUpdate, error) {
+	var msg depthUpdateEvent
+	if err := json.Unmarshal(p, &msg); err != nil {
+		return connector.OrderBookUpdate{}, err
+	}
+
+	bids, err := parseChanges(msg.Bids)
+	if err != nil {
+		return connector.OrderBookUpdate{}, fmt.Errorf("failed to parse msg.Bids: %w", err)
+	}
+
+	asks, err := parseChanges(msg.Asks)
+	if err != nil {
+		return connector.OrderBookUpdate{}, fmt.Errorf("failed to parse msg.Asks: %w", err)
+	}
+
+	return connector.OrderBookUpdate{
+		Symbol:        msg.Symbol,
+		FirstSequence: msg.FirstUpdateID,
+		LastSequence:  msg.FinalUpdateID,
+		Bids:          bids,
+		Asks:          asks,
+		Timestamp:     time.UnixMilli(msg.Timestamp),
+	}, nil
+}
+
+// SnapshotDepth returns the number of levels requested in an order book snapshot.
+func (e *exchange) SnapshotDepth() int {
+	return depthSnapshotLimit
+}
+
+// OrderBookSnapshot gets an order book snapshot for the given symbol from Binance.
+func (e *exchange) OrderBookSnapshot(ctx context.Context, symbol string) (entities.OrderBook, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.hostname+DepthRoute, nil)
+	if err != nil {
+		return entities.OrderBook{}, err
+	}
+
+	q := req.URL.Query()
+	q.Add("symbol", symbol)
+	q.Add("limit", fmt.Sprint(depthSnapshotLimit))
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return entities.OrderBook{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return entities.OrderBook{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return entities.OrderBook{}, fmt.Errorf("non-ok status: %s", resp.Status)
+	}
+
+	var depthResp depthResponse
+	if err = json.Unmarshal(body, &depthResp); err != nil {
+		return entities.OrderBook{}, err
+	}
+
+	bids, err := parseLevels(depthResp.Bids)
+	if err != nil {
+		return entities.OrderBook{}, fmt.Errorf("failed to parse bids: %w", err)
+	}
+
+	asks, err := parseLevels(depthResp.Asks)
+	if err != nil {
+		return entities.OrderBook{}, fmt.Errorf("failed to parse asks: %w", err)
+	}
+
+	return entities.OrderBook{
+		Bids:     bids,
+		Asks:     asks,
+		Sequence: depthResp.LastUpdateID,
+	}, nil
+}
+
+// parseLevels parses [price, quantity] pairs into order book levels.
+func parseLevels(levels [][]string) ([]entities.OrderBookLevel, error) {
+	parsed := make([]entities.OrderBookLevel, 0, len(levels))
+
+	for _, l := range levels {
+		if len(l) < 2 {
+			return nil, fmt.Errorf("invalid level %v", l)
+		}
+
+		price, err := decimal.NewFromString(l[0])
+		if err != nil {
+			return nil, err
+		}
+
+		quantity, err := decimal.NewFromString(l[1])
+		if err != nil {
+			return nil, err
+		}
+
+		parsed = append(parsed, entities.OrderBookLevel{Price: price, Quantity: quantity})
+	}
+
+	return parsed, nil
+}
+
+// parseChanges parses [price, quantity] pairs into order book changes.
+func parseChanges(levels [][]string) ([]connector.OrderBookChange, error) {
+	parsed, err := parseLevels(levels)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]connector.OrderBookChange, 0, len(parsed))
+	for _, l := range parsed {
+		changes = append(changes, connector.OrderBookChange{Price: l.Price, Quantity: l.Quantity})
+	}
+
+	return changes, nil
+}
+
+// depthResponse represents the JSON returned by Binance when requesting an order book snapshot.
+type depthResponse struct {
+	LastUpdateID int64      `json:"lastUpdateId"`
+	Bids         [][]string `json:"bids"`
+	Asks         [][]string `json:"asks"`
+}
+
+type depthUpdateEvent struct {
+	Type          string     `json:"e"`
+	Timestamp     int64      `json:"E"`
+	Symbol        string     `json:"s"`
+	FirstUpdateID int64      `json:"U"`
+	FinalUpdateID int64      `json:"u"`
+	Bids          [][]string `json:"b"`
+	Asks          [][]string `json:"a"`
+}
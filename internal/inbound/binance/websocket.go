@@ -1,175 +1,163 @@
 package binance
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
-	"github.com/rs/zerolog/log"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
 	"github.com/shopspring/decimal"
 )
 
-type WebSocket interface {
-	Close() error
-	ReadMessage() (messageType int, p []byte, err error)
-	WriteJSON(v interface{}) error
+// exchange implements the Binance websocket protocol for connector.Client.
+type exchange struct {
+	apiKey       string
+	hostname     string
+	httpClient   http.Client
+	pingInterval time.Duration
+	websocketURL string
 }
 
-type WebsocketClientConfig struct {
+type ExchangeConfig struct {
 	APIKey       string
 	Hostname     string
 	HTTPClient   http.Client
 	PingInterval time.Duration
-	TradingPairs []string // e.g. ["BTC/BUSD", "ETH/BUSD"]
-	UseCases     MarketUpdaterUseCases
 	WebsocketURL string
 }
 
-type WebsocketClient struct {
-	apiKey              string
-	binanceSymbolToPair map[string]string // BASEQUOTE --> BASE/QUOTE
-	hostname            string
-	httpClient          http.Client
-	listenKey           string
-	pingInterval        time.Duration
-	useCases            MarketUpdaterUseCases
-	websocketURL        string
-	ws                  WebSocket
-}
-
-func NewWebsocket(cfg WebsocketClientConfig) (*WebsocketClient, error) {
+// NewExchange returns the Binance websocket protocol, for a connector.Client.
+// Every 60 minutes at most, we must ping Binance with a "ping" connection, so it's kept alive.
+func NewExchange(cfg ExchangeConfig) connector.OrderBookExchange {
 	if cfg.PingInterval == 0 {
 		// Default to 20 minutes, Binance requires a ping every 60 minutes
 		cfg.PingInterval = 20 * time.Minute
 	}
 
-	c := &WebsocketClient{
-		apiKey:              cfg.APIKey,
-		binanceSymbolToPair: make(map[string]string),
-		httpClient:          cfg.HTTPClient,
-		hostname:            cfg.Hostname,
-		pingInterval:        cfg.PingInterval,
-		useCases:            cfg.UseCases,
-		websocketURL:        cfg.WebsocketURL,
+	return &exchange{
+		apiKey:       cfg.APIKey,
+		hostname:     cfg.Hostname,
+		httpClient:   cfg.HTTPClient,
+		pingInterval: cfg.PingInterval,
+		websocketURL: cfg.WebsocketURL,
 	}
+}
 
-	for _, pair := range cfg.TradingPairs {
-		s := strings.Split(pair, "/")
-		if len(s) != 2 {
-			return nil, fmt.Errorf("invalid pair %s", pair)
-		}
+func (e *exchange) Name() entities.Exchange {
+	return entities.ExchangeBinance
+}
+
+// Symbol returns the Binance symbol for a trading pair, e.g. BTCUSDT.
+func (e *exchange) Symbol(pair entities.Pair) string {
+	return entities.ExchangeBinance.EncodeSymbol(pair)
+}
 
-		c.binanceSymbolToPair[s[0]+s[1]] = pair
+// Decode decodes "24hrTicker", "depthUpdate" and "aggTrade" messages. Other messages are ignored.
+func (e *exchange) Decode(p []byte) (connector.Message, error) {
+	var event eventHeader
+	if err := json.Unmarshal(p, &event); err != nil {
+		return connector.Message{}, err
 	}
 
-	return c, nil
+	switch event.Type {
+	case "24hrTicker":
+		t, err := decodeTicker(p)
+		if err != nil {
+			return connector.Message{}, err
+		}
+		return connector.Message{Tickers: []connector.Ticker{t}}, nil
+	case "depthUpdate":
+		u, err := decodeDepthUpdate(p)
+		if err != nil {
+			return connector.Message{}, err
+		}
+		return connector.Message{OrderBookUpdates: []connector.OrderBookUpdate{u}}, nil
+	case "aggTrade":
+		t, err := decodeTrade(p)
+		if err != nil {
+			return connector.Message{}, err
+		}
+		return connector.Message{Trades: []connector.Trade{t}}, nil
+	default:
+		return connector.Message{}, nil
+	}
 }
 
-// Run listens to an opened connection to a websocket provided by Binance, subscribed to markets.
-// It also reconnects if the websocket is closed by Binance.
-// Every 60 minutes at most, we must ping Binance with a "ping" connection, so it's kept alive.
-func (c *WebsocketClient) Run(ctx context.Context) error {
-	for {
-		select {
-		case <-ctx.Done():
-			log.Info().Msg("Context canceled, stopping websocket run")
-			return ctx.Err()
-		default:
-		}
+// decodeTicker decodes a "24hrTicker" message.
+func decodeTicker(p []byte) (connector.Ticker, error) {
+	var msg ticker24hrEvent
+	if err := json.Unmarshal(p, &msg); err != nil {
+		return connector.Ticker{}, err
+	}
 
-		if err := c.init(ctx); err != nil {
-			return fmt.Errorf("failed to init websocket: %w", err)
-		}
+	lastPrice, err := decimal.NewFromString(msg.LastPrice)
+	if err != nil {
+		return connector.Ticker{}, fmt.Errorf("failed to parse msg.LastPrice: %w", err)
+	}
 
-		if err := c.listen(ctx); err != nil {
-			log.Err(err).Msg("Error while listening to websocket")
-		}
+	bestBuyPrice, err := decimal.NewFromString(msg.BestBuyPrice)
+	if err != nil {
+		return connector.Ticker{}, fmt.Errorf("failed to parse msg.BestBuyPrice: %w", err)
+	}
 
-		if err := c.ws.Close(); err != nil {
-			log.Err(err).Msg("Error when closing WebSocket")
-		}
+	bestSellPrice, err := decimal.NewFromString(msg.BestSellPrice)
+	if err != nil {
+		return connector.Ticker{}, fmt.Errorf("failed to parse msg.BestSellPrice: %w", err)
+	}
+
+	tradeAmount, err := strconv.ParseFloat(msg.TradeAmountInQuoteAsset, 64)
+	if err != nil {
+		return connector.Ticker{}, fmt.Errorf("failed to parse msg.TradeAmountInQuoteAsset: %w", err)
 	}
+
+	return connector.Ticker{
+		Symbol: msg.Symbol,
+		Market: entities.Market{
+			BestBuyPrice:    bestBuyPrice,
+			BestSellPrice:   bestSellPrice,
+			LastTradedPrice: lastPrice,
+			Timestamp:       time.UnixMilli(msg.Timestamp),
+			Volume24hr:      tradeAmount,
+		},
+	}, nil
 }
 
-// listen for "24hrTicker" messages on Binance WebSocket and updates the price for the corresponding trading pair.
-// If message is not a "24hrTicker", ignore. Listens until an error occurs or the context is cancelled.
-func (c *WebsocketClient) listen(ctx context.Context) error {
-	for {
-		select {
-		case <-ctx.Done():
-			log.Info().Msg("Context canceled, stopping websocket listener")
-			return ctx.Err()
-		default:
-			messageType, p, err := c.ws.ReadMessage()
-			if err != nil {
-				log.Err(err).Msg("Failed to read message")
-				return err
-			}
-
-			if messageType == websocket.PongMessage {
-				continue
-			}
-
-			var msg ticker24hrEvent
-			if err = json.Unmarshal(p, &msg); err != nil {
-				log.Err(err).Interface("msg", string(p)).Msg("Failed to unmarshal msg to JSON")
-				continue
-			}
-
-			if msg.Type != "24hrTicker" {
-				continue
-			}
-
-			lastPrice, err := decimal.NewFromString(msg.LastPrice)
-			if err != nil {
-				log.Err(err).Interface("msg", msg).Msg("Failed to parse msg.LatestPrice")
-				continue
-			}
-
-			bestBuyPrice, err := decimal.NewFromString(msg.BestBuyPrice)
-			if err != nil {
-				log.Err(err).Interface("msg", msg).Msg("Failed to parse msg.BestBuyPrice")
-				continue
-			}
-
-			bestSellPrice, err := decimal.NewFromString(msg.BestSellPrice)
-			if err != nil {
-				log.Err(err).Interface("msg", msg).Msg("Failed to parse msg.BestSellPrice")
-				continue
-			}
-
-			tradeAmount, err := strconv.ParseFloat(msg.TradeAmountInQuoteAsset, 64)
-			if err != nil {
-				log.Err(err).Interface("msg", msg).Msg("Failed to parse msg.TradeAmountInQuoteAsset")
-				continue
-			}
-
-			pair, ok := c.binanceSymbolToPair[msg.Symbol]
-			if !ok {
-				log.Warn().Str("symbol", msg.Symbol).Interface("message", msg).Msg("Received data for unknown symbol")
-				continue
-			}
-
-			err = c.useCases.UpdateMarket(ctx, entities.Market{
-				TradingPair:     pair,
-				Exchange:        entities.ExchangeBinance,
-				BestBuyPrice:    bestBuyPrice,
-				BestSellPrice:   bestSellPrice,
-				LastTradedPrice: lastPrice,
-				Timestamp:       time.UnixMilli(msg.Timestamp),
-				Volume24hr:      tradeAmount,
-			})
-			if err != nil {
-				log.Err(err).Interface("msg", msg).Msg("Failed to update market")
-			}
-		}
+// decodeTrade decodes an "aggTrade" message, the trades of one taker order at one price.
+func decodeTrade(p []byte) (connector.Trade, error) {
+	var msg aggTradeEvent
+	if err := json.Unmarshal(p, &msg); err != nil {
+		return connector.Trade{}, err
+	}
+
+	price, err := decimal.NewFromString(msg.Price)
+	if err != nil {
+		return connector.Trade{}, fmt.Errorf("failed to parse msg.Price: %w", err)
+	}
+
+	quantity, err := decimal.NewFromString(msg.Quantity)
+	if err != nil {
+		return connector.Trade{}, fmt.Errorf("failed to parse msg.Quantity: %w", err)
 	}
+
+	return connector.Trade{
+		Symbol: msg.Symbol,
+		Trade: entities.Trade{
+			ID:        msg.ID,
+			Price:     price,
+			Quantity:  quantity,
+			Timestamp: time.UnixMilli(msg.TradeTime),
+		},
+	}, nil
+}
+
+// eventHeader holds the fields common to all Binance stream events.
+// Both are needed, as JSON keys are matched case-insensitively.
+type eventHeader struct {
+	Type      string `json:"e"`
+	Timestamp int64  `json:"E"`
 }
 
 type ticker24hrEvent struct {
@@ -186,3 +174,14 @@ type ticker24hrEvent struct {
 	UpperQ                  interface{} `json:"Q"` // Don't remove
 	UpperS                  string      `json:"S"` // Don't remove
 }
+
+type aggTradeEvent struct {
+	Type      string      `json:"e"`
+	Timestamp int64       `json:"E"`
+	Symbol    string      `json:"s"`
+	ID        int64       `json:"a"`
+	Price     string      `json:"p"`
+	Quantity  string      `json:"q"`
+	TradeTime int64       `json:"T"`
+	UpperM    interface{} `json:"M"` // Don't remove
+}
@@ -0,0 +1,80 @@
+package bybit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+)
+
+const (
+	// pingInterval is how often a ping is sent, as recommended by Bybit.
+	pingInterval = 20 * time.Second
+
+	// maxSubscriptionArgs is the number of topics Bybit accepts in one spot subscription request.
+	maxSubscriptionArgs = 10
+
+	tickersTopic   = "tickers."
+	orderBookTopic = "orderbook.1."
+	tradeTopic     = "publicTrade."
+
+	// snapshotType is the type of order book messages holding the whole book, rather than changes to it.
+	snapshotType = "snapshot"
+)
+
+// Bootstrap returns the websocket URL. Bybit's public feed needs no authentication.
+// Quotes merged from the previous connection are dropped, as updates were missed while disconnected.
+func (e *exchange) Bootstrap(_ context.Context) (connector.Connection, error) {
+	e.quotes = make(map[string]*quote)
+
+	return connector.Connection{
+		URL:               e.websocketURL,
+		KeepaliveInterval: pingInterval,
+	}, nil
+}
+
+// SubscribeMessages returns messages subscribing to the tickers, level 1 order book and trade topics of every symbol.
+func (e *exchange) SubscribeMessages(symbols []string) ([][]byte, error) {
+	return subscriptionMessages("subscribe", symbols)
+}
+
+// UnsubscribeMessages returns messages unsubscribing from the tickers, level 1 order book and trade topics of every
+// symbol.
+func (e *exchange) UnsubscribeMessages(symbols []string) ([][]byte, error) {
+	return subscriptionMessages("unsubscribe", symbols)
+}
+
+func subscriptionMessages(op string, symbols []string) ([][]byte, error) {
+	var topics []string
+	for _, symbol := range symbols {
+		topics = append(topics, tickersTopic+symbol, orderBookTopic+symbol, tradeTopic+symbol)
+	}
+
+	var msgs [][]byte
+	for start := 0; start < len(topics); start += maxSubscriptionArgs {
+		end := min(start+maxSubscriptionArgs, len(topics))
+
+		msg, err := json.Marshal(&wsRequest{
+			Op:   op,
+			Args: topics[start:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, nil
+}
+
+// KeepaliveMessage returns a ping message.
+func (e *exchange) KeepaliveMessage() ([]byte, error) {
+	return json.Marshal(&wsRequest{Op: "ping"})
+}
+
+type wsRequest struct {
+	Op   string   `json:"op"`
+	Args []string `json:"args,omitempty"`
+}
@@ -0,0 +1,137 @@
+package bybit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+// InstrumentsInfoRoute returns every spot instrument in one page, as spot doesn't support pagination.
+const InstrumentsInfoRoute = "/v5/market/instruments-info?category=spot"
+
+type MetadataConfig struct {
+	Hostname   string
+	HTTPClient http.Client
+}
+
+// Metadata gets what's listed on Bybit from its public REST API.
+type Metadata struct {
+	hostname   string
+	httpClient http.Client
+}
+
+func NewMetadata(cfg MetadataConfig) *Metadata {
+	return &Metadata{
+		hostname:   cfg.Hostname,
+		httpClient: cfg.HTTPClient,
+	}
+}
+
+func (m *Metadata) Exchange() entities.Exchange {
+	return entities.ExchangeBybit
+}
+
+// GetInstruments returns the order constraints of every spot symbol on Bybit.
+func (m *Metadata) GetInstruments(ctx context.Context) ([]entities.Instrument, error) {
+	var result instrumentsResult
+	if err := m.get(ctx, InstrumentsInfoRoute, &result); err != nil {
+		return nil, fmt.Errorf("failed to get instruments: %w", err)
+	}
+
+	instruments := make([]entities.Instrument, 0, len(result.List))
+	for _, info := range result.List {
+		pair, err := entities.NewPair(info.BaseCoin, info.QuoteCoin)
+		if err != nil {
+			log.Debug().Err(err).Str("symbol", info.Symbol).Msg("Skipping symbol")
+			continue
+		}
+
+		instruments = append(instruments, entities.Instrument{
+			Exchange:    entities.ExchangeBybit,
+			TradingPair: pair.String(),
+			Status:      info.status(),
+			TickSize:    info.PriceFilter.TickSize,
+			StepSize:    info.LotSizeFilter.BasePrecision,
+			MinQuantity: info.LotSizeFilter.MinOrderQty,
+			MaxQuantity: info.LotSizeFilter.MaxOrderQty,
+			MinNotional: info.LotSizeFilter.MinOrderAmt,
+		})
+	}
+
+	return instruments, nil
+}
+
+// get decodes the result of a GET request to a route.
+func (m *Metadata) get(ctx context.Context, route string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.hostname+route, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-ok status: %s", resp.Status)
+	}
+
+	restResp := restResponse{Result: result}
+	if err = json.Unmarshal(body, &restResp); err != nil {
+		return err
+	}
+
+	if restResp.RetCode != 0 {
+		return fmt.Errorf("error code %d: %s", restResp.RetCode, restResp.RetMsg)
+	}
+
+	return nil
+}
+
+// restResponse wraps the result of every Bybit REST response.
+type restResponse struct {
+	RetCode int    `json:"retCode"`
+	RetMsg  string `json:"retMsg"`
+	Result  any    `json:"result"`
+}
+
+type instrumentsResult struct {
+	List []instrumentInfo `json:"list"`
+}
+
+type instrumentInfo struct {
+	Symbol        string `json:"symbol"` // e.g. BTCUSDT
+	BaseCoin      string `json:"baseCoin"`
+	QuoteCoin     string `json:"quoteCoin"`
+	Status        string `json:"status"` // e.g. Trading or PreLaunch
+	LotSizeFilter struct {
+		BasePrecision decimal.Decimal `json:"basePrecision"` // Quantity increment
+		MinOrderQty   decimal.Decimal `json:"minOrderQty"`
+		MaxOrderQty   decimal.Decimal `json:"maxOrderQty"`
+		MinOrderAmt   decimal.Decimal `json:"minOrderAmt"` // Min order value in the quote coin
+	} `json:"lotSizeFilter"`
+	PriceFilter struct {
+		TickSize decimal.Decimal `json:"tickSize"`
+	} `json:"priceFilter"`
+}
+
+func (i instrumentInfo) status() entities.ListingStatus {
+	if i.Status == "Trading" {
+		return entities.ListingStatusTrading
+	}
+
+	return entities.ListingStatusHalted
+}
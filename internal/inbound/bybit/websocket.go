@@ -0,0 +1,266 @@
+package bybit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+	"github.com/shopspring/decimal"
+)
+
+// exchange implements the Bybit v5 spot websocket protocol for connector.Client.
+// Spot tickers don't include the best bid and ask, so they're merged with the level 1 order book of the same symbol.
+type exchange struct {
+	websocketURL string
+
+	// Set when the websocket starts.
+	quotes map[string]*quote // BASEQUOTE --> latest merged ticker and level 1 order book
+}
+
+// quote is the latest market data received for a symbol.
+type quote struct {
+	lastPrice     decimal.Decimal
+	volume        float64
+	bestBuyPrice  decimal.Decimal
+	bestSellPrice decimal.Decimal
+	hasTicker     bool
+	hasOrderBook  bool
+}
+
+type ExchangeConfig struct {
+	WebsocketURL string
+}
+
+// NewExchange returns the Bybit websocket protocol, for a connector.Client.
+func NewExchange(cfg ExchangeConfig) connector.Exchange {
+	return &exchange{
+		quotes:       make(map[string]*quote),
+		websocketURL: cfg.WebsocketURL,
+	}
+}
+
+func (e *exchange) Name() entities.Exchange {
+	return entities.ExchangeBybit
+}
+
+// Symbol returns the Bybit symbol for a trading pair, e.g. BTCUSDT.
+func (e *exchange) Symbol(pair entities.Pair) string {
+	return entities.ExchangeBybit.EncodeSymbol(pair)
+}
+
+// Decode decodes "tickers" and "orderbook.1" messages, returning a ticker once both have been received for a symbol,
+// and "publicTrade" messages. Other messages are ignored.
+func (e *exchange) Decode(p []byte) (connector.Message, error) {
+	var msg wsMessage
+	if err := json.Unmarshal(p, &msg); err != nil {
+		return connector.Message{}, err
+	}
+
+	// Replies to requests, e.g. "subscribe" and "ping"
+	if msg.Op != "" {
+		if !msg.Success {
+			return connector.Message{}, fmt.Errorf("%s request failed: %s", msg.Op, msg.RetMsg)
+		}
+		return connector.Message{}, nil
+	}
+
+	var (
+		symbol string
+		err    error
+	)
+
+	switch {
+	case strings.HasPrefix(msg.Topic, tickersTopic):
+		symbol, err = e.applyTicker(msg.Data)
+	case strings.HasPrefix(msg.Topic, orderBookTopic):
+		symbol, err = e.applyOrderBook(msg.Type, msg.Data)
+	case strings.HasPrefix(msg.Topic, tradeTopic):
+		return decodeTrades(msg.Data)
+	default:
+		return connector.Message{}, nil
+	}
+	if err != nil {
+		return connector.Message{}, err
+	}
+
+	q := e.quotes[symbol]
+	if !q.hasTicker || !q.hasOrderBook {
+		return connector.Message{}, nil
+	}
+
+	return connector.Message{
+		Tickers: []connector.Ticker{{
+			Symbol: symbol,
+			Market: entities.Market{
+				BestBuyPrice:    q.bestBuyPrice,
+				BestSellPrice:   q.bestSellPrice,
+				LastTradedPrice: q.lastPrice,
+				Timestamp:       time.UnixMilli(msg.Timestamp),
+				Volume24hr:      q.volume,
+			},
+		}},
+	}, nil
+}
+
+// applyTicker stores the last price and volume of a "tickers" message, returning its symbol.
+func (e *exchange) applyTicker(data json.RawMessage) (string, error) {
+	var t ticker
+	if err := json.Unmarshal(data, &t); err != nil {
+		return "", err
+	}
+
+	lastPrice, err := decimal.NewFromString(t.LastPrice)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ticker.LastPrice: %w", err)
+	}
+
+	// turnover24h is in the quote asset, volume24h in the base asset
+	volume, err := strconv.ParseFloat(t.Turnover24h, 64)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse ticker.Turnover24h: %w", err)
+	}
+
+	q := e.quote(t.Symbol)
+	q.lastPrice = lastPrice
+	q.volume = volume
+	q.hasTicker = true
+
+	return t.Symbol, nil
+}
+
+// applyOrderBook stores the best bid and ask of an "orderbook.1" message, returning its symbol.
+// A snapshot replaces both sides, so a side with no level is empty. A delta only changes the sides it lists, and a
+// level with a zero size empties its side.
+func (e *exchange) applyOrderBook(typ string, data json.RawMessage) (string, error) {
+	var ob orderBook
+	if err := json.Unmarshal(data, &ob); err != nil {
+		return "", err
+	}
+
+	q := e.quote(ob.Symbol)
+
+	if typ == snapshotType {
+		q.bestBuyPrice = decimal.Zero
+		q.bestSellPrice = decimal.Zero
+	}
+
+	var err error
+	if q.bestBuyPrice, err = bestPrice(q.bestBuyPrice, ob.Bids); err != nil {
+		return "", fmt.Errorf("failed to parse bid: %w", err)
+	}
+
+	if q.bestSellPrice, err = bestPrice(q.bestSellPrice, ob.Asks); err != nil {
+		return "", fmt.Errorf("failed to parse ask: %w", err)
+	}
+
+	// One side of the book is empty, so there's no market to quote
+	q.hasOrderBook = !q.bestBuyPrice.IsZero() && !q.bestSellPrice.IsZero()
+
+	return ob.Symbol, nil
+}
+
+// bestPrice applies the levels of one side of the book to its best price, returning zero once the side is empty.
+func bestPrice(current decimal.Decimal, levels [][2]string) (decimal.Decimal, error) {
+	for _, l := range levels {
+		price, err := decimal.NewFromString(l[0])
+		if err != nil {
+			return decimal.Zero, err
+		}
+
+		size, err := decimal.NewFromString(l[1])
+		if err != nil {
+			return decimal.Zero, err
+		}
+
+		switch {
+		case !size.IsZero():
+			current = price
+		case price.Equal(current):
+			current = decimal.Zero
+		}
+	}
+
+	return current, nil
+}
+
+// decodeTrades decodes the data of a "publicTrade" message, oldest trade first.
+func decodeTrades(data json.RawMessage) (connector.Message, error) {
+	var ts []trade
+	if err := json.Unmarshal(data, &ts); err != nil {
+		return connector.Message{}, err
+	}
+
+	trades := make([]connector.Trade, 0, len(ts))
+	for _, t := range ts {
+		price, err := decimal.NewFromString(t.Price)
+		if err != nil {
+			return connector.Message{}, fmt.Errorf("failed to parse trade.Price: %w", err)
+		}
+
+		quantity, err := decimal.NewFromString(t.Volume)
+		if err != nil {
+			return connector.Message{}, fmt.Errorf("failed to parse trade.Volume: %w", err)
+		}
+
+		// Spot trade IDs are numbers, but aren't documented as such, so one that isn't is left as zero
+		id, _ := strconv.ParseInt(t.ID, 10, 64)
+
+		trades = append(trades, connector.Trade{
+			Symbol: t.Symbol,
+			Trade: entities.Trade{
+				ID:        id,
+				Price:     price,
+				Quantity:  quantity,
+				Timestamp: time.UnixMilli(t.Timestamp),
+			},
+		})
+	}
+
+	return connector.Message{Trades: trades}, nil
+}
+
+// quote returns the quote for a symbol, creating it if needed.
+func (e *exchange) quote(symbol string) *quote {
+	q, ok := e.quotes[symbol]
+	if !ok {
+		q = &quote{}
+		e.quotes[symbol] = q
+	}
+
+	return q
+}
+
+type wsMessage struct {
+	Topic     string          `json:"topic"`
+	Type      string          `json:"type"` // "snapshot" or "delta"
+	Timestamp int64           `json:"ts"`   // Unix milliseconds
+	Data      json.RawMessage `json:"data"`
+	Op        string          `json:"op"` // Set on replies to requests
+	Success   bool            `json:"success"`
+	RetMsg    string          `json:"ret_msg"`
+}
+
+type ticker struct {
+	Symbol      string `json:"symbol"`
+	LastPrice   string `json:"lastPrice"`
+	Turnover24h string `json:"turnover24h"`
+}
+
+type orderBook struct {
+	Symbol string      `json:"s"`
+	Bids   [][2]string `json:"b"` // [price, size]
+	Asks   [][2]string `json:"a"`
+}
+
+type trade struct {
+	Timestamp int64  `json:"T"` // Unix milliseconds
+	Symbol    string `json:"s"`
+	Side      string `json:"S"` // Don't remove, "s" would be decoded into it
+	Volume    string `json:"v"` // In the base asset
+	Price     string `json:"p"`
+	ID        string `json:"i"`
+}
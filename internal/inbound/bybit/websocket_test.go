@@ -0,0 +1,74 @@
+package bybit
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchange_Decode(t *testing.T) {
+	const (
+		ticker   = `{"topic":"tickers.BTCUSDT","ts":1700000000000,"data":{"symbol":"BTCUSDT","lastPrice":"100.5","turnover24h":"1000"}}`
+		snapshot = `{"topic":"orderbook.1.BTCUSDT","type":"snapshot","ts":1700000000001,"data":{"s":"BTCUSDT","b":[["100","1"]],"a":[["101","2"]]}}`
+	)
+
+	setup := func(t *testing.T) *exchange {
+		e := &exchange{quotes: make(map[string]*quote)}
+
+		_, err := e.Decode([]byte(ticker))
+		require.NoError(t, err)
+
+		msg, err := e.Decode([]byte(snapshot))
+		require.NoError(t, err)
+		require.Len(t, msg.Tickers, 1)
+		require.True(t, decimal.NewFromInt(100).Equal(msg.Tickers[0].Market.BestBuyPrice))
+		require.True(t, decimal.NewFromInt(101).Equal(msg.Tickers[0].Market.BestSellPrice))
+
+		return e
+	}
+
+	t.Run("quotes the best bid and ask of a delta", func(t *testing.T) {
+		e := setup(t)
+
+		msg, err := e.Decode([]byte(`{"topic":"orderbook.1.BTCUSDT","type":"delta","ts":1700000000002,"data":{"s":"BTCUSDT","b":[["99","1"]],"a":[]}}`))
+		require.NoError(t, err)
+		require.Len(t, msg.Tickers, 1)
+		require.True(t, decimal.NewFromInt(99).Equal(msg.Tickers[0].Market.BestBuyPrice))
+		require.True(t, decimal.NewFromInt(101).Equal(msg.Tickers[0].Market.BestSellPrice))
+	})
+
+	t.Run("skips a snapshot with an empty side", func(t *testing.T) {
+		e := setup(t)
+
+		msg, err := e.Decode([]byte(`{"topic":"orderbook.1.BTCUSDT","type":"snapshot","ts":1700000000002,"data":{"s":"BTCUSDT","b":[["100","1"]],"a":[]}}`))
+		require.NoError(t, err)
+		require.Empty(t, msg.Tickers)
+
+		// Nor quotes the emptied side on the next ticker
+		msg, err = e.Decode([]byte(ticker))
+		require.NoError(t, err)
+		require.Empty(t, msg.Tickers)
+	})
+
+	t.Run("skips a delta that empties a side", func(t *testing.T) {
+		e := setup(t)
+
+		msg, err := e.Decode([]byte(`{"topic":"orderbook.1.BTCUSDT","type":"delta","ts":1700000000002,"data":{"s":"BTCUSDT","b":[["100","0"]],"a":[]}}`))
+		require.NoError(t, err)
+		require.Empty(t, msg.Tickers)
+	})
+
+	t.Run("decodes trades", func(t *testing.T) {
+		e := &exchange{quotes: make(map[string]*quote)}
+
+		msg, err := e.Decode([]byte(`{"topic":"publicTrade.BTCUSDT","type":"snapshot","ts":1700000000003,"data":[` +
+			`{"T":1700000000002,"s":"BTCUSDT","S":"Buy","v":"0.25","p":"100.5","L":"PlusTick","i":"2290000000061666327","BT":false}]}`))
+		require.NoError(t, err)
+		require.Len(t, msg.Trades, 1)
+		require.Equal(t, "BTCUSDT", msg.Trades[0].Symbol)
+		require.Equal(t, int64(2290000000061666327), msg.Trades[0].Trade.ID)
+		require.True(t, decimal.NewFromFloat(100.5).Equal(msg.Trades[0].Trade.Price))
+		require.True(t, decimal.NewFromFloat(0.25).Equal(msg.Trades[0].Trade.Quantity))
+	})
+}
@@ -0,0 +1,48 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+)
+
+// Bootstrap returns the websocket URL. Coinbase's public feed needs no authentication or application level pings.
+func (e *exchange) Bootstrap(_ context.Context) (connector.Connection, error) {
+	return connector.Connection{URL: e.websocketURL}, nil
+}
+
+// SubscribeMessages returns a message subscribing to the ticker, matches and heartbeat channels for every symbol.
+// Tickers are only sent when a product trades, so heartbeats show a quiet product's subscription is still alive.
+func (e *exchange) SubscribeMessages(symbols []string) ([][]byte, error) {
+	return subscriptionMessages("subscribe", symbols)
+}
+
+// UnsubscribeMessages returns a message unsubscribing from the ticker, matches and heartbeat channels for every symbol.
+func (e *exchange) UnsubscribeMessages(symbols []string) ([][]byte, error) {
+	return subscriptionMessages("unsubscribe", symbols)
+}
+
+func subscriptionMessages(typ string, symbols []string) ([][]byte, error) {
+	msg, err := json.Marshal(&subscriptionRequest{
+		Type:       typ,
+		ProductIDs: symbols,
+		Channels:   []string{"ticker", "matches", "heartbeat"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{msg}, nil
+}
+
+// KeepaliveMessage isn't needed, Coinbase keeps the connection alive with ping frames.
+func (e *exchange) KeepaliveMessage() ([]byte, error) {
+	return nil, nil
+}
+
+type subscriptionRequest struct {
+	Type       string   `json:"type"`
+	ProductIDs []string `json:"product_ids"`
+	Channels   []string `json:"channels"`
+}
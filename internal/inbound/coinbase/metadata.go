@@ -0,0 +1,111 @@
+package coinbase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+const ProductsRoute = "/products"
+
+type MetadataConfig struct {
+	Hostname   string
+	HTTPClient http.Client
+}
+
+// Metadata gets what's listed on Coinbase Exchange from its public REST API.
+type Metadata struct {
+	hostname   string
+	httpClient http.Client
+}
+
+func NewMetadata(cfg MetadataConfig) *Metadata {
+	return &Metadata{
+		hostname:   cfg.Hostname,
+		httpClient: cfg.HTTPClient,
+	}
+}
+
+func (m *Metadata) Exchange() entities.Exchange {
+	return entities.ExchangeCoinbase
+}
+
+// GetInstruments returns the order constraints of every product on Coinbase. Coinbase no longer limits the size of
+// orders, only their value, so only MinNotional is set of the limits.
+func (m *Metadata) GetInstruments(ctx context.Context) ([]entities.Instrument, error) {
+	var products []product
+	if err := m.get(ctx, ProductsRoute, &products); err != nil {
+		return nil, fmt.Errorf("failed to get products: %w", err)
+	}
+
+	instruments := make([]entities.Instrument, 0, len(products))
+	for _, p := range products {
+		pair, err := entities.NewPair(p.BaseCurrency, p.QuoteCurrency)
+		if err != nil {
+			log.Debug().Err(err).Str("product", p.ID).Msg("Skipping product")
+			continue
+		}
+
+		instruments = append(instruments, entities.Instrument{
+			Exchange:    entities.ExchangeCoinbase,
+			TradingPair: pair.String(),
+			Status:      p.status(),
+			TickSize:    p.QuoteIncrement,
+			StepSize:    p.BaseIncrement,
+			MinNotional: p.MinMarketFunds,
+		})
+	}
+
+	return instruments, nil
+}
+
+// get decodes the JSON response of a GET request to a route.
+func (m *Metadata) get(ctx context.Context, route string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.hostname+route, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-ok status: %s", resp.Status)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+type product struct {
+	ID              string          `json:"id"` // e.g. BTC-USD
+	BaseCurrency    string          `json:"base_currency"`
+	QuoteCurrency   string          `json:"quote_currency"`
+	Status          string          `json:"status"` // e.g. online or delisted
+	TradingDisabled bool            `json:"trading_disabled"`
+	CancelOnly      bool            `json:"cancel_only"`
+	QuoteIncrement  decimal.Decimal `json:"quote_increment"`
+	BaseIncrement   decimal.Decimal `json:"base_increment"`
+	MinMarketFunds  decimal.Decimal `json:"min_market_funds"` // Min order value in the quote currency
+}
+
+func (p product) status() entities.ListingStatus {
+	if p.Status == "online" && !p.TradingDisabled && !p.CancelOnly {
+		return entities.ListingStatusTrading
+	}
+
+	return entities.ListingStatusHalted
+}
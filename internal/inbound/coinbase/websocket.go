@@ -0,0 +1,139 @@
+package coinbase
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+	"github.com/shopspring/decimal"
+)
+
+// exchange implements the Coinbase websocket protocol for connector.Client.
+type exchange struct {
+	websocketURL string
+}
+
+type ExchangeConfig struct {
+	WebsocketURL string
+}
+
+// NewExchange returns the Coinbase websocket protocol, for a connector.Client.
+func NewExchange(cfg ExchangeConfig) connector.Exchange {
+	return &exchange{websocketURL: cfg.WebsocketURL}
+}
+
+func (e *exchange) Name() entities.Exchange {
+	return entities.ExchangeCoinbase
+}
+
+// Symbol returns the Coinbase product ID for a trading pair, e.g. BTC-USD.
+func (e *exchange) Symbol(pair entities.Pair) string {
+	return entities.ExchangeCoinbase.EncodeSymbol(pair)
+}
+
+// Decode decodes "ticker", "match" and "heartbeat" messages. Other messages are ignored, apart from "error" messages,
+// e.g. subscribing to an unknown product, which are returned as errors. The "last_match" sent on subscribing is
+// ignored too, as it traded before the subscription and may already have been counted.
+func (e *exchange) Decode(p []byte) (connector.Message, error) {
+	var msg feedMessage
+	if err := json.Unmarshal(p, &msg); err != nil {
+		return connector.Message{}, err
+	}
+
+	switch msg.Type {
+	case "error":
+		return connector.Message{}, fmt.Errorf("received error from Coinbase: %s: %s", msg.Message, msg.Reason)
+	case "heartbeat":
+		return connector.Message{Heartbeat: true}, nil
+	case "ticker":
+		t, err := decodeTicker(msg)
+		if err != nil {
+			return connector.Message{}, err
+		}
+		return connector.Message{Tickers: []connector.Ticker{t}}, nil
+	case "match":
+		t, err := decodeMatch(msg)
+		if err != nil {
+			return connector.Message{}, err
+		}
+		return connector.Message{Trades: []connector.Trade{t}}, nil
+	default:
+		return connector.Message{}, nil
+	}
+}
+
+// decodeTicker decodes a "ticker" message.
+func decodeTicker(msg feedMessage) (connector.Ticker, error) {
+	lastPrice, err := decimal.NewFromString(msg.Price)
+	if err != nil {
+		return connector.Ticker{}, fmt.Errorf("failed to parse msg.Price: %w", err)
+	}
+
+	bestBuyPrice, err := decimal.NewFromString(msg.BestBid)
+	if err != nil {
+		return connector.Ticker{}, fmt.Errorf("failed to parse msg.BestBid: %w", err)
+	}
+
+	bestSellPrice, err := decimal.NewFromString(msg.BestAsk)
+	if err != nil {
+		return connector.Ticker{}, fmt.Errorf("failed to parse msg.BestAsk: %w", err)
+	}
+
+	volume, err := decimal.NewFromString(msg.Volume24h)
+	if err != nil {
+		return connector.Ticker{}, fmt.Errorf("failed to parse msg.Volume24h: %w", err)
+	}
+
+	// Coinbase only reports the base asset traded, so it's converted to the quote asset at the last price
+	volume24hr, _ := volume.Mul(lastPrice).Float64()
+
+	return connector.Ticker{
+		Symbol: msg.ProductID,
+		Market: entities.Market{
+			BestBuyPrice:    bestBuyPrice,
+			BestSellPrice:   bestSellPrice,
+			LastTradedPrice: lastPrice,
+			Timestamp:       msg.Time,
+			Volume24hr:      volume24hr,
+		},
+	}, nil
+}
+
+// decodeMatch decodes a "match" message, a trade of the product.
+func decodeMatch(msg feedMessage) (connector.Trade, error) {
+	price, err := decimal.NewFromString(msg.Price)
+	if err != nil {
+		return connector.Trade{}, fmt.Errorf("failed to parse msg.Price: %w", err)
+	}
+
+	quantity, err := decimal.NewFromString(msg.Size)
+	if err != nil {
+		return connector.Trade{}, fmt.Errorf("failed to parse msg.Size: %w", err)
+	}
+
+	return connector.Trade{
+		Symbol: msg.ProductID,
+		Trade: entities.Trade{
+			ID:        msg.TradeID,
+			Price:     price,
+			Quantity:  quantity,
+			Timestamp: msg.Time,
+		},
+	}, nil
+}
+
+type feedMessage struct {
+	Type      string    `json:"type"`
+	ProductID string    `json:"product_id"`
+	Price     string    `json:"price"`
+	BestBid   string    `json:"best_bid"`   // Set on "ticker" messages
+	BestAsk   string    `json:"best_ask"`   // Set on "ticker" messages
+	Volume24h string    `json:"volume_24h"` // Set on "ticker" messages
+	TradeID   int64     `json:"trade_id"`
+	Size      string    `json:"size"` // Set on "match" messages
+	Time      time.Time `json:"time"`
+	Message   string    `json:"message"` // Set on "error" messages, e.g. "Failed to subscribe"
+	Reason    string    `json:"reason"`  // Set on "error" messages, e.g. "BTC-XYZ is not a valid product"
+}
@@ -0,0 +1,57 @@
+package connector
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff is the policy for reconnecting to an exchange after a connection fails or is closed.
+// The delay doubles after each failed connection, up to MaxDelay, and is reset once a connection stays up
+// for ResetAfter, so a brief outage is retried quickly without hammering an exchange that's down.
+type Backoff struct {
+	InitialDelay time.Duration // Delay before the first reconnect, defaults to 1s
+	MaxDelay     time.Duration // Longest delay between reconnects, defaults to 1m
+	ResetAfter   time.Duration // Connections that stay up this long reset the delay, defaults to 1m
+}
+
+// backoff tracks the delay before the next reconnect.
+type backoff struct {
+	policy Backoff
+	delay  time.Duration
+}
+
+func newBackoff(policy Backoff) *backoff {
+	if policy.InitialDelay == 0 {
+		// Default
+		policy.InitialDelay = time.Second
+	}
+
+	if policy.MaxDelay == 0 {
+		// Default
+		policy.MaxDelay = time.Minute
+	}
+
+	if policy.ResetAfter == 0 {
+		// Default
+		policy.ResetAfter = time.Minute
+	}
+
+	return &backoff{
+		policy: policy,
+		delay:  policy.InitialDelay,
+	}
+}
+
+// next returns the delay before reconnecting after a connection that lasted for uptime.
+// The delay is jittered between half and all of the current delay, so updaters don't reconnect in lockstep.
+func (b *backoff) next(uptime time.Duration) time.Duration {
+	if uptime >= b.policy.ResetAfter {
+		b.delay = b.policy.InitialDelay
+	}
+
+	delay := b.delay/2 + rand.N(b.delay/2+1)
+
+	b.delay = min(b.delay*2, b.policy.MaxDelay)
+
+	return delay
+}
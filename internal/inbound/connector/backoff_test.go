@@ -0,0 +1,38 @@
+package connector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoff_Next(t *testing.T) {
+	policy := Backoff{
+		InitialDelay: time.Second,
+		MaxDelay:     5 * time.Second,
+		ResetAfter:   time.Minute,
+	}
+
+	t.Run("doubles the delay up to the max, with jitter", func(t *testing.T) {
+		b := newBackoff(policy)
+
+		for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second} {
+			delay := b.next(0)
+			require.GreaterOrEqual(t, delay, want/2)
+			require.LessOrEqual(t, delay, want)
+		}
+	})
+
+	t.Run("resets the delay after a healthy connection", func(t *testing.T) {
+		b := newBackoff(policy)
+
+		for range 4 {
+			b.next(0)
+		}
+
+		delay := b.next(time.Minute)
+		require.GreaterOrEqual(t, delay, time.Second/2)
+		require.LessOrEqual(t, delay, time.Second)
+	})
+}
@@ -0,0 +1,376 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	arberrors "github.com/peterstirrup/arbenheimer/internal/domain/errors"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/peterstirrup/arbenheimer/internal/inbound/connector")
+
+type WebSocket interface {
+	Close() error
+	ReadMessage() (messageType int, p []byte, err error)
+	SetPingHandler(h func(appData string) error)
+	SetPongHandler(h func(appData string) error)
+	SetReadDeadline(t time.Time) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	WriteMessage(messageType int, data []byte) error
+}
+
+type Config struct {
+	Exchange       Exchange
+	OrderBookDepth int      // Number of levels stored on each side of the order book
+	Reconnect      Backoff  // Optional, see Backoff for defaults
+	TradingPairs   []string // e.g. ["BTC/USDT", "ETH/USDT"]
+	UseCases       MarketUpdaterUseCases
+	Watchdog       Watchdog // Optional, see Watchdog for defaults
+}
+
+type Client struct {
+	backoff        *backoff
+	exchange       Exchange
+	log            zerolog.Logger
+	orderBookDepth int
+	useCases       MarketUpdaterUseCases
+	watchdog       Watchdog
+
+	// Changed by SetTradingPairs while running.
+	subMu        sync.Mutex // Held while subscribing, so pairs can't change between subscribing and being connected
+	pairsMu      sync.RWMutex
+	symbolToPair map[string]string // Exchange symbol --> BASE/QUOTE
+
+	// Reported by health checks, kept across reconnects.
+	connected  atomic.Bool
+	lastUpdate atomic.Int64 // Unix nanoseconds of the last ticker or order book update
+
+	// Set when the websocket starts.
+	lastData     atomic.Int64 // Unix nanoseconds of the last ticker or order book update, read by the watchdog
+	ws           WebSocket
+	writeMu      sync.Mutex // Websockets support one concurrent writer
+	booksMu      sync.Mutex
+	orderBooks   map[string]*localOrderBook   // Exchange symbol --> local order book, synced from a snapshot
+	pendingBooks map[string]*pendingOrderBook // Exchange symbol --> updates waiting for a snapshot
+}
+
+// NewClient creates a new websocket client for the given exchange.
+func NewClient(cfg Config) (*Client, error) {
+	if cfg.OrderBookDepth == 0 {
+		// Default
+		cfg.OrderBookDepth = 50
+	}
+
+	c := &Client{
+		backoff:        newBackoff(cfg.Reconnect),
+		exchange:       cfg.Exchange,
+		log:            log.With().Str("exchange", cfg.Exchange.Name().String()).Logger(),
+		orderBookDepth: cfg.OrderBookDepth,
+		useCases:       cfg.UseCases,
+		watchdog:       newWatchdog(cfg.Watchdog),
+	}
+
+	// Counted from the start, so the feed isn't dead before it's had a chance to connect
+	c.lastUpdate.Store(time.Now().UnixNano())
+
+	symbolToPair, err := c.symbolsFor(cfg.TradingPairs)
+	if err != nil {
+		return nil, err
+	}
+	c.symbolToPair = symbolToPair
+
+	return c, nil
+}
+
+// Run starts the websocket client and blocks until the context is cancelled.
+// Reconnects with backoff if the connection can't be started or is closed by the exchange.
+func (c *Client) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			c.log.Info().Msg("Context canceled, stopping websocket run")
+			return ctx.Err()
+		default:
+		}
+
+		start := time.Now()
+
+		err := c.runConnection(ctx)
+		if ctx.Err() != nil {
+			continue
+		}
+
+		delay := c.backoff.next(time.Since(start))
+		c.log.Warn().Err(err).Dur("delay", delay).Msg("Websocket disconnected, reconnecting")
+		reconnects.WithLabelValues(c.exchange.Name().String()).Inc()
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+}
+
+// runConnection connects to the exchange and listens until the connection fails, the watchdog ends it or the context
+// is cancelled. Returns the error that ended the connection.
+func (c *Client) runConnection(ctx context.Context) error {
+	connCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	if err := c.init(connCtx); err != nil {
+		return fmt.Errorf("failed to init websocket: %w", err)
+	}
+
+	go c.watch(connCtx, cancel, c.ws)
+
+	err := c.listen(connCtx)
+	c.connected.Store(false)
+
+	if ctx.Err() != nil {
+		// Shutting down rather than reconnecting, so leave the exchange cleanly
+		c.unsubscribe()
+	}
+
+	if closeErr := c.ws.Close(); closeErr != nil {
+		c.log.Err(closeErr).Msg("Error closing WebSocket")
+	}
+
+	return err
+}
+
+// init bootstraps and dials a new connection, subscribes to the trading pairs and starts sending keepalives.
+func (c *Client) init(ctx context.Context) error {
+	conn, err := c.exchange.Bootstrap(ctx)
+	if err != nil {
+		return err
+	}
+
+	ws, resp, err := websocket.DefaultDialer.DialContext(ctx, conn.URL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to dial websocket: %w", err)
+	}
+	resp.Body.Close()
+
+	c.writeMu.Lock()
+	c.ws = ws
+	c.writeMu.Unlock()
+
+	c.handleControlFrames(ctx, ws)
+	c.lastData.Store(time.Now().UnixNano())
+
+	// Updates were missed while disconnected, so order books must be synced from a new snapshot
+	c.booksMu.Lock()
+	c.orderBooks = make(map[string]*localOrderBook)
+	c.pendingBooks = make(map[string]*pendingOrderBook)
+	c.booksMu.Unlock()
+
+	// Connected as soon as the pairs are subscribed, so SetTradingPairs changes them on the connection from then on
+	c.subMu.Lock()
+	err = c.subscribe()
+	if err == nil {
+		c.connected.Store(true)
+	}
+	c.subMu.Unlock()
+
+	if err != nil {
+		ws.Close()
+		return err
+	}
+
+	if conn.KeepaliveInterval > 0 {
+		go c.keepAlive(ctx, conn.KeepaliveInterval)
+	}
+
+	return nil
+}
+
+// subscribe sends the exchange's subscription messages for every trading pair.
+func (c *Client) subscribe() error {
+	symbols := c.symbols()
+
+	if err := c.sendSubscriptions(c.exchange.SubscribeMessages, symbols); err != nil {
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	c.log.Info().Strs("symbols", symbols).Msg("Subscribed to symbols")
+
+	return nil
+}
+
+// unsubscribe sends the exchange's unsubscribe messages for every trading pair, then a close frame.
+// Failures are only logged, as the connection is closed either way.
+func (c *Client) unsubscribe() {
+	if err := c.sendSubscriptions(c.exchange.UnsubscribeMessages, c.symbols()); err != nil {
+		c.log.Err(err).Msg("Failed to unsubscribe")
+	}
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := c.ws.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(writeWait)); err != nil {
+		c.log.Err(err).Msg("Failed to send close frame")
+		return
+	}
+
+	c.log.Info().Msg("Unsubscribed from symbols")
+}
+
+// keepAlive sends the exchange's keepalive message every interval until the context is cancelled.
+func (c *Client) keepAlive(ctx context.Context, interval time.Duration) {
+	pt := time.NewTicker(interval)
+	defer pt.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-pt.C:
+			msg, err := c.exchange.KeepaliveMessage()
+			if err != nil {
+				c.log.Err(err).Msg("Failed to create keepalive message")
+				continue
+			}
+
+			if err = c.write(msg); err != nil {
+				c.log.Err(err).Msg("Failed to send keepalive message")
+			}
+		}
+	}
+}
+
+func (c *Client) write(msg []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	return c.ws.WriteMessage(websocket.TextMessage, msg)
+}
+
+// listen to the websocket, updating markets and order books when a message is received.
+// Listens until an error occurs or the context is cancelled.
+func (c *Client) listen(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			c.log.Info().Msg("Context canceled, stopping websocket listener")
+			return context.Cause(ctx)
+		default:
+			if err := c.extendReadDeadline(ctx, c.ws); err != nil {
+				return err
+			}
+
+			messageType, p, err := c.ws.ReadMessage()
+			if err != nil {
+				if ctx.Err() != nil {
+					// Ended by the watchdog or cancellation, rather than the exchange
+					return context.Cause(ctx)
+				}
+				c.log.Err(err).Msg("Failed to read message")
+				return err
+			}
+
+			if messageType == websocket.PongMessage {
+				continue
+			}
+
+			c.handleMessage(ctx, p)
+		}
+	}
+}
+
+// handleMessage decodes a websocket message, then updates the markets, order books and candles it contains.
+func (c *Client) handleMessage(ctx context.Context, p []byte) {
+	ctx, span := tracer.Start(ctx, "connector.receive", trace.WithAttributes(
+		attribute.String("exchange", c.exchange.Name().String()),
+	))
+	defer span.End()
+
+	msg, err := c.exchange.Decode(p)
+	if err != nil {
+		c.log.Err(err).Interface("msg", string(p)).Msg("Failed to decode message")
+		decodeFailures.WithLabelValues(c.exchange.Name().String()).Inc()
+		span.SetStatus(codes.Error, err.Error())
+		return
+	}
+
+	if len(msg.Tickers) > 0 || len(msg.OrderBookUpdates) > 0 || len(msg.Trades) > 0 || msg.Heartbeat {
+		now := time.Now().UnixNano()
+		c.lastData.Store(now)
+		c.lastUpdate.Store(now)
+	}
+
+	for _, t := range msg.Tickers {
+		c.updateMarket(ctx, t)
+	}
+
+	for _, u := range msg.OrderBookUpdates {
+		c.updateOrderBook(ctx, u)
+	}
+
+	for _, t := range msg.Trades {
+		c.addTrade(ctx, t)
+	}
+}
+
+// updateMarket updates the market for the trading pair of a ticker.
+func (c *Client) updateMarket(ctx context.Context, t Ticker) {
+	pair, ok := c.pair(t.Symbol)
+	if !ok {
+		c.log.Warn().Str("symbol", t.Symbol).Msg("Received data for unknown symbol")
+		return
+	}
+
+	market := t.Market
+	market.TradingPair = pair
+	market.Exchange = c.exchange.Name()
+
+	latency := time.Since(market.Timestamp)
+
+	messagesReceived.WithLabelValues(market.Exchange.String(), pair, "ticker").Inc()
+	ingestLatency.WithLabelValues(market.Exchange.String()).Observe(latency.Seconds())
+
+	trace.SpanFromContext(ctx).AddEvent("ticker", trace.WithAttributes(
+		attribute.String("trading_pair", pair),
+		attribute.String("exchange_timestamp", market.Timestamp.Format(time.RFC3339Nano)),
+		attribute.Int64("ingest_latency_us", latency.Microseconds()),
+	))
+
+	// A write in progress finishes when shutting down, so the market isn't left half updated
+	if err := c.useCases.UpdateMarket(context.WithoutCancel(ctx), market); err != nil {
+		c.log.Err(err).Interface("market", market).Msg("Failed to update market")
+
+		reason := rejectedStoreError
+		if errors.Is(err, arberrors.ErrInvalidMarketTimestamp) {
+			reason = rejectedOutOfOrder
+		}
+		marketUpdateRejections.WithLabelValues(market.Exchange.String(), reason).Inc()
+	}
+}
+
+// addTrade adds a trade to the candles of its trading pair.
+func (c *Client) addTrade(ctx context.Context, t Trade) {
+	pair, ok := c.pair(t.Symbol)
+	if !ok {
+		c.log.Warn().Str("symbol", t.Symbol).Msg("Received data for unknown symbol")
+		return
+	}
+
+	trade := t.Trade
+	trade.TradingPair = pair
+	trade.Exchange = c.exchange.Name()
+
+	messagesReceived.WithLabelValues(trade.Exchange.String(), pair, "trade").Inc()
+
+	// A write in progress finishes when shutting down, so the trade isn't left half added
+	if err := c.useCases.AddTrade(context.WithoutCancel(ctx), trade); err != nil {
+		c.log.Err(err).Interface("trade", trade).Msg("Failed to add trade")
+	}
+}
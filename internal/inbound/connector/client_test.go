@@ -0,0 +1,206 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeExchange subscribes with plain text messages, e.g. "subscribe BTC-USDT", failing to create the first
+// subscribeFailures and unsubscribeFailures of them.
+type fakeExchange struct {
+	url                 string
+	subscribeFailures   int
+	unsubscribeFailures int
+}
+
+func (e *fakeExchange) Name() entities.Exchange { return "fake" }
+
+func (e *fakeExchange) Symbol(pair entities.Pair) string {
+	return pair.Base.String() + "-" + pair.Quote.String()
+}
+
+func (e *fakeExchange) Bootstrap(context.Context) (Connection, error) {
+	return Connection{URL: e.url}, nil
+}
+
+func (e *fakeExchange) SubscribeMessages(symbols []string) ([][]byte, error) {
+	if e.subscribeFailures > 0 {
+		e.subscribeFailures--
+		return nil, errors.New("rate limited")
+	}
+
+	return [][]byte{[]byte("subscribe " + strings.Join(symbols, ","))}, nil
+}
+
+func (e *fakeExchange) UnsubscribeMessages(symbols []string) ([][]byte, error) {
+	if e.unsubscribeFailures > 0 {
+		e.unsubscribeFailures--
+		return nil, errors.New("rate limited")
+	}
+
+	return [][]byte{[]byte("unsubscribe " + strings.Join(symbols, ","))}, nil
+}
+
+func (e *fakeExchange) Decode([]byte) (Message, error) { return Message{}, nil }
+
+func (e *fakeExchange) KeepaliveMessage() ([]byte, error) { return nil, nil }
+
+func TestClient_Run(t *testing.T) {
+	t.Run("unsubscribes and closes the connection when the context is cancelled", func(t *testing.T) {
+		url, received := newTestServer(t)
+
+		c, err := NewClient(Config{
+			Exchange:     &fakeExchange{url: url},
+			TradingPairs: []string{"BTC/USDT"},
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		done := make(chan error)
+		go func() {
+			done <- c.Run(ctx)
+		}()
+
+		require.Equal(t, "subscribe BTC-USDT", receive(t, received))
+
+		cancel()
+
+		require.ErrorIs(t, <-done, context.Canceled)
+		require.Equal(t, "unsubscribe BTC-USDT", receive(t, received))
+		require.Equal(t, "close", receive(t, received))
+	})
+}
+
+func TestClient_SetTradingPairs(t *testing.T) {
+	t.Run("changes subscriptions on the live connection", func(t *testing.T) {
+		url, received := newTestServer(t)
+
+		c, err := NewClient(Config{
+			Exchange:     &fakeExchange{url: url},
+			TradingPairs: []string{"BTC/USDT", "ETH/USDT"},
+		})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go c.Run(ctx)
+
+		require.Contains(t, []string{"subscribe BTC-USDT,ETH-USDT", "subscribe ETH-USDT,BTC-USDT"}, receive(t, received))
+		require.Eventually(t, func() bool { return c.CheckConnected(ctx) == nil }, time.Second, time.Millisecond)
+
+		err = c.SetTradingPairs([]string{"ETH/USDT", "SOL/USDT"})
+		require.NoError(t, err)
+
+		require.Equal(t, "subscribe SOL-USDT", receive(t, received))
+		require.Equal(t, "unsubscribe BTC-USDT", receive(t, received))
+
+		_, ok := c.pair("BTC-USDT")
+		require.False(t, ok)
+		pair, ok := c.pair("SOL-USDT")
+		require.True(t, ok)
+		require.Equal(t, "SOL/USDT", pair)
+	})
+
+	t.Run("sends the changes that failed when called again", func(t *testing.T) {
+		url, received := newTestServer(t)
+
+		e := &fakeExchange{url: url}
+		c, err := NewClient(Config{Exchange: e, TradingPairs: []string{"BTC/USDT"}})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		go c.Run(ctx)
+
+		require.Equal(t, "subscribe BTC-USDT", receive(t, received))
+		require.Eventually(t, func() bool { return c.CheckConnected(ctx) == nil }, time.Second, time.Millisecond)
+
+		e.subscribeFailures, e.unsubscribeFailures = 1, 1
+
+		require.EqualError(t, c.SetTradingPairs([]string{"SOL/USDT"}), "failed to subscribe: rate limited")
+		require.Equal(t, []string{"BTC-USDT"}, c.symbols())
+
+		require.EqualError(t, c.SetTradingPairs([]string{"SOL/USDT"}), "failed to unsubscribe: rate limited")
+		require.Equal(t, "subscribe SOL-USDT", receive(t, received))
+		require.ElementsMatch(t, []string{"BTC-USDT", "SOL-USDT"}, c.symbols())
+
+		require.NoError(t, c.SetTradingPairs([]string{"SOL/USDT"}))
+		require.Equal(t, "unsubscribe BTC-USDT", receive(t, received))
+		require.Equal(t, []string{"SOL-USDT"}, c.symbols())
+	})
+
+	t.Run("drops the order books of removed pairs", func(t *testing.T) {
+		c, err := NewClient(Config{Exchange: &fakeExchange{}, TradingPairs: []string{"BTC/USDT", "ETH/USDT"}})
+		require.NoError(t, err)
+
+		c.orderBooks = map[string]*localOrderBook{"BTC-USDT": {}, "ETH-USDT": {}}
+		c.pendingBooks = map[string]*pendingOrderBook{"BTC-USDT": {}}
+
+		require.NoError(t, c.SetTradingPairs([]string{"ETH/USDT"}))
+
+		require.Equal(t, map[string]*localOrderBook{"ETH-USDT": {}}, c.orderBooks)
+		require.Empty(t, c.pendingBooks)
+	})
+
+	t.Run("rejects invalid pairs without changing subscriptions", func(t *testing.T) {
+		c, err := NewClient(Config{Exchange: &fakeExchange{}, TradingPairs: []string{"BTC/USDT"}})
+		require.NoError(t, err)
+
+		require.EqualError(t, c.SetTradingPairs([]string{"BTCUSDT"}), "invalid pair BTCUSDT")
+		require.Equal(t, []string{"BTC-USDT"}, c.symbols())
+	})
+}
+
+// newTestServer starts a websocket server that sends every message it receives, and "close" for a normal close frame,
+// to the returned channel.
+func newTestServer(t *testing.T) (string, chan string) {
+	t.Helper()
+
+	received := make(chan string, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		for {
+			_, p, err := ws.ReadMessage()
+			if err != nil {
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+					received <- "close"
+				}
+				return
+			}
+			received <- string(p)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), received
+}
+
+func receive(t *testing.T, received chan string) string {
+	t.Helper()
+
+	select {
+	case msg := <-received:
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+		return ""
+	}
+}
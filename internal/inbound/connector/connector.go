@@ -0,0 +1,91 @@
+// Package connector runs exchange websocket feeds. It owns the connection lifecycle (bootstrap, dial, subscribe,
+// keepalive, listen and reconnect) and local order book syncing, so an exchange only implements its protocol.
+package connector
+
+import (
+	"context"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/shopspring/decimal"
+)
+
+// Exchange is the protocol specific part of an exchange websocket feed.
+type Exchange interface {
+	// Name returns the exchange the feed is for.
+	Name() entities.Exchange
+	// Symbol returns the exchange's symbol for a trading pair, e.g. BTC/USDT --> "BTC-USDT".
+	Symbol(pair entities.Pair) string
+	// Bootstrap prepares a new connection, e.g. requesting a token, and returns where to connect.
+	// ctx is cancelled when the connection closes, so can be used for work that lasts as long as the connection.
+	Bootstrap(ctx context.Context) (Connection, error)
+	// SubscribeMessages returns the messages that subscribe to the given symbols.
+	SubscribeMessages(symbols []string) ([][]byte, error)
+	// UnsubscribeMessages returns the messages that unsubscribe from the given symbols.
+	UnsubscribeMessages(symbols []string) ([][]byte, error)
+	// Decode decodes a message from the websocket. Messages with no market data return an empty Message.
+	Decode(p []byte) (Message, error)
+	// KeepaliveMessage returns the message sent every Connection.KeepaliveInterval.
+	KeepaliveMessage() ([]byte, error)
+}
+
+// OrderBookExchange is implemented by exchanges that send order book updates, so they can be synced from a snapshot.
+type OrderBookExchange interface {
+	Exchange
+	// OrderBookSnapshot returns the order book for a symbol, with its Sequence set to the last update included.
+	OrderBookSnapshot(ctx context.Context, symbol string) (entities.OrderBook, error)
+	// SnapshotDepth returns the most levels on each side of a snapshot, or 0 if snapshots are the full order book.
+	SnapshotDepth() int
+}
+
+type MarketUpdaterUseCases interface {
+	UpdateMarket(ctx context.Context, market entities.Market) error
+	UpdateOrderBook(ctx context.Context, orderBook entities.OrderBook) error
+	AddTrade(ctx context.Context, trade entities.Trade) error
+}
+
+// Connection describes how to connect to an exchange websocket.
+type Connection struct {
+	URL               string
+	KeepaliveInterval time.Duration // Zero if the exchange doesn't need keepalive messages
+}
+
+// Message is the market data decoded from a websocket message.
+type Message struct {
+	Tickers          []Ticker
+	OrderBookUpdates []OrderBookUpdate
+	Trades           []Trade
+	// Heartbeat is set on the exchange's heartbeat messages. Feeds that only send a ticker when a pair trades, e.g.
+	// Coinbase and Kraken, can be quiet for longer than the data timeout, so their heartbeats count as data.
+	Heartbeat bool
+}
+
+// Ticker is top of book market data for an exchange symbol.
+type Ticker struct {
+	Symbol string
+	Market entities.Market // TradingPair and Exchange are set by the Client
+}
+
+// Trade is a trade of an exchange symbol, in the order the exchange sent it.
+type Trade struct {
+	Symbol string
+	Trade  entities.Trade // TradingPair and Exchange are set by the Client
+}
+
+// OrderBookUpdate is a set of order book changes covering the exchange sequence numbers
+// FirstSequence to LastSequence.
+type OrderBookUpdate struct {
+	Symbol        string
+	FirstSequence int64
+	LastSequence  int64
+	Bids          []OrderBookChange
+	Asks          []OrderBookChange
+	Timestamp     time.Time
+}
+
+// OrderBookChange sets the quantity at a price. A zero quantity removes the level.
+type OrderBookChange struct {
+	Price    decimal.Decimal
+	Quantity decimal.Decimal
+	Sequence int64 // Optional, changes at or before the order book's sequence are skipped
+}
@@ -0,0 +1,31 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errNotConnected = errors.New("websocket not connected")
+
+// CheckConnected returns an error if the websocket isn't connected, e.g. while waiting to reconnect.
+func (c *Client) CheckConnected(context.Context) error {
+	if !c.connected.Load() {
+		return errNotConnected
+	}
+
+	return nil
+}
+
+// CheckAlive returns an error if no market data or heartbeats have been received for longer than Watchdog.DeadAfter.
+// Reconnecting doesn't reset it, so it fails if the feed has died in a way reconnecting hasn't fixed.
+func (c *Client) CheckAlive(context.Context) error {
+	last := time.Unix(0, c.lastUpdate.Load())
+
+	if since := time.Since(last); since > c.watchdog.DeadAfter {
+		return fmt.Errorf("no market data for %s", since.Round(time.Second))
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package connector
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Rejection reasons for UpdateMarket.
+const (
+	rejectedOutOfOrder = "out_of_order" // A newer market is already stored
+	rejectedStoreError = "store_error"
+)
+
+var (
+	messagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "arbenheimer",
+		Subsystem: "exchange",
+		Name:      "messages_received_total",
+		Help:      "Tickers, order book updates and trades received, by exchange and trading pair.",
+	}, []string{"exchange", "trading_pair", "type"})
+
+	decodeFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "arbenheimer",
+		Subsystem: "exchange",
+		Name:      "decode_failures_total",
+		Help:      "Websocket messages that couldn't be decoded.",
+	}, []string{"exchange"})
+
+	marketUpdateRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "arbenheimer",
+		Subsystem: "exchange",
+		Name:      "market_update_rejections_total",
+		Help:      "Tickers that weren't stored, by reason.",
+	}, []string{"exchange", "reason"})
+
+	reconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "arbenheimer",
+		Subsystem: "exchange",
+		Name:      "reconnects_total",
+		Help:      "Websocket reconnects, after a failed connection or one that was closed.",
+	}, []string{"exchange"})
+
+	ingestLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "arbenheimer",
+		Subsystem: "exchange",
+		Name:      "ingest_latency_seconds",
+		Help:      "Time from a ticker's exchange timestamp to it being received.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"exchange"})
+)
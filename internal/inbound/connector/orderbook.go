@@ -0,0 +1,194 @@
+package connector
+
+import (
+	"context"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/shopspring/decimal"
+)
+
+// maxBufferedUpdates is how many updates are buffered for a symbol while waiting for its snapshot. If the snapshot
+// takes longer, the buffer is dropped and syncing starts again.
+const maxBufferedUpdates = 1000
+
+// pendingOrderBook buffers the updates for a symbol while its snapshot is fetched.
+type pendingOrderBook struct {
+	snapshot chan snapshotResult // Buffered, so the fetch never blocks
+	updates  []OrderBookUpdate
+}
+
+type snapshotResult struct {
+	orderBook entities.OrderBook
+	err       error
+}
+
+// localOrderBook is an order book synced from a snapshot. A snapshot of only the top levels says nothing about the
+// prices past its deepest level, so changes there are ignored rather than leaving gaps in the book.
+type localOrderBook struct {
+	entities.OrderBook
+	bidFloor   decimal.Decimal // Lowest known bid price, zero if the snapshot had every bid
+	askCeiling decimal.Decimal // Highest known ask price, zero if the snapshot had every ask
+}
+
+// newLocalOrderBook returns the local order book for a snapshot of at most depth levels on each side.
+func newLocalOrderBook(ob entities.OrderBook, depth int) *localOrderBook {
+	l := &localOrderBook{OrderBook: ob}
+
+	if depth > 0 && len(ob.Bids) >= depth {
+		l.bidFloor = ob.Bids[len(ob.Bids)-1].Price
+	}
+
+	if depth > 0 && len(ob.Asks) >= depth {
+		l.askCeiling = ob.Asks[len(ob.Asks)-1].Price
+	}
+
+	return l
+}
+
+// updateOrderBook applies an update to the local order book for its trading pair and stores the result.
+// The local order book is synced from the exchange's snapshot first, buffering updates until it arrives. If an update
+// is missed, the order book is dropped and synced again from a new snapshot on the next update.
+func (c *Client) updateOrderBook(ctx context.Context, u OrderBookUpdate) {
+	obe, ok := c.exchange.(OrderBookExchange)
+	if !ok {
+		return
+	}
+
+	pair, ok := c.pair(u.Symbol)
+	if !ok {
+		c.log.Warn().Str("symbol", u.Symbol).Msg("Received order book for unknown symbol")
+		return
+	}
+
+	messagesReceived.WithLabelValues(c.exchange.Name().String(), pair, "order_book").Inc()
+
+	c.booksMu.Lock()
+	defer c.booksMu.Unlock()
+
+	ob, ok := c.orderBooks[u.Symbol]
+	if ok {
+		if !c.applyUpdate(ob, u) {
+			return
+		}
+	} else if ob = c.syncOrderBook(ctx, obe, pair, u); ob == nil {
+		return
+	}
+
+	if err := c.useCases.UpdateOrderBook(context.WithoutCancel(ctx), ob.Truncate(c.orderBookDepth)); err != nil {
+		c.log.Err(err).Str("symbol", u.Symbol).Msg("Failed to update order book")
+	}
+}
+
+// syncOrderBook buffers an update for a symbol with no order book, fetching its snapshot in the background. Once the
+// snapshot has arrived, the buffered updates it doesn't include are applied to it, as Binance's local order book
+// procedure requires, and the synced order book is returned. Returns nil until then.
+func (c *Client) syncOrderBook(ctx context.Context, obe OrderBookExchange, pair string, u OrderBookUpdate) *localOrderBook {
+	p, ok := c.pendingBooks[u.Symbol]
+	if !ok {
+		p = &pendingOrderBook{}
+		c.pendingBooks[u.Symbol] = p
+		c.fetchSnapshot(ctx, obe, u.Symbol, p)
+	}
+
+	p.updates = append(p.updates, u)
+	if len(p.updates) > maxBufferedUpdates {
+		c.log.Warn().Str("symbol", u.Symbol).Msg("Order book snapshot too slow, syncing again")
+		delete(c.pendingBooks, u.Symbol)
+		return nil
+	}
+
+	var res snapshotResult
+	select {
+	case res = <-p.snapshot:
+	default:
+		return nil
+	}
+
+	if res.err != nil {
+		c.log.Err(res.err).Str("symbol", u.Symbol).Msg("Failed to get order book snapshot")
+		delete(c.pendingBooks, u.Symbol)
+		return nil
+	}
+
+	ob := newLocalOrderBook(res.orderBook, obe.SnapshotDepth())
+	ob.TradingPair = pair
+	ob.Exchange = c.exchange.Name()
+
+	// Already included in the snapshot
+	updates := p.updates
+	for len(updates) > 0 && updates[0].LastSequence <= ob.Sequence {
+		updates = updates[1:]
+	}
+
+	// Older than the buffered updates, so keep them and fetch a newer one
+	if len(updates) > 0 && updates[0].FirstSequence > ob.Sequence+1 {
+		c.log.Debug().Str("symbol", u.Symbol).Int64("sequence", ob.Sequence).Int64("firstSequence", updates[0].FirstSequence).Msg("Order book snapshot older than updates, fetching again")
+		p.updates = updates
+		c.fetchSnapshot(ctx, obe, u.Symbol, p)
+		return nil
+	}
+
+	delete(c.pendingBooks, u.Symbol)
+	c.orderBooks[u.Symbol] = ob
+
+	for _, bu := range updates {
+		if _, ok = c.orderBooks[u.Symbol]; !ok {
+			// Dropped by a missed update
+			return nil
+		}
+		c.applyUpdate(ob, bu)
+	}
+
+	return ob
+}
+
+// fetchSnapshot gets the order book snapshot for a symbol in the background, sending it to the pending order book.
+func (c *Client) fetchSnapshot(ctx context.Context, obe OrderBookExchange, symbol string, p *pendingOrderBook) {
+	snapshot := make(chan snapshotResult, 1)
+	p.snapshot = snapshot
+
+	go func() {
+		ob, err := obe.OrderBookSnapshot(ctx, symbol)
+		snapshot <- snapshotResult{orderBook: ob, err: err}
+	}()
+}
+
+// applyUpdate applies an update to an order book, returning whether it changed. If an update was missed, or every
+// known level on a side has gone, the order book is dropped so it's synced again.
+func (c *Client) applyUpdate(ob *localOrderBook, u OrderBookUpdate) bool {
+	// Already included in the snapshot
+	if u.LastSequence <= ob.Sequence {
+		return false
+	}
+
+	// Missed an update
+	if u.FirstSequence > ob.Sequence+1 {
+		c.log.Warn().Str("symbol", u.Symbol).Int64("sequence", ob.Sequence).Int64("firstSequence", u.FirstSequence).Msg("Order book out of sync")
+		delete(c.orderBooks, u.Symbol)
+		return false
+	}
+
+	for _, ch := range u.Bids {
+		if (ch.Sequence == 0 || ch.Sequence > ob.Sequence) && (ob.bidFloor.IsZero() || ch.Price.GreaterThanOrEqual(ob.bidFloor)) {
+			ob.UpdateBid(ch.Price, ch.Quantity)
+		}
+	}
+
+	for _, ch := range u.Asks {
+		if (ch.Sequence == 0 || ch.Sequence > ob.Sequence) && (ob.askCeiling.IsZero() || ch.Price.LessThanOrEqual(ob.askCeiling)) {
+			ob.UpdateAsk(ch.Price, ch.Quantity)
+		}
+	}
+
+	ob.Sequence = u.LastSequence
+	ob.Timestamp = u.Timestamp
+
+	// The price has moved past the snapshot
+	if !ob.bidFloor.IsZero() && len(ob.Bids) == 0 || !ob.askCeiling.IsZero() && len(ob.Asks) == 0 {
+		c.log.Warn().Str("symbol", u.Symbol).Msg("Order book moved past its snapshot, syncing again")
+		delete(c.orderBooks, u.Symbol)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,156 @@
+package connector
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOrderBookExchange returns the snapshots sent to it, one per request.
+type fakeOrderBookExchange struct {
+	fakeExchange
+	snapshots chan entities.OrderBook
+	depth     int
+}
+
+func (e *fakeOrderBookExchange) SnapshotDepth() int { return e.depth }
+
+func (e *fakeOrderBookExchange) OrderBookSnapshot(ctx context.Context, _ string) (entities.OrderBook, error) {
+	select {
+	case ob := <-e.snapshots:
+		return ob, nil
+	case <-ctx.Done():
+		return entities.OrderBook{}, ctx.Err()
+	}
+}
+
+// fakeUseCases keeps the last order book stored.
+type fakeUseCases struct {
+	orderBook chan entities.OrderBook
+}
+
+func (u *fakeUseCases) UpdateMarket(context.Context, entities.Market) error { return nil }
+
+func (u *fakeUseCases) AddTrade(context.Context, entities.Trade) error { return nil }
+
+func (u *fakeUseCases) UpdateOrderBook(_ context.Context, ob entities.OrderBook) error {
+	u.orderBook <- ob
+	return nil
+}
+
+func level(price float64) entities.OrderBookLevel {
+	return entities.OrderBookLevel{Price: decimal.NewFromFloat(price), Quantity: decimal.NewFromInt(1)}
+}
+
+func bidUpdate(first, last int64, price float64) OrderBookUpdate {
+	return OrderBookUpdate{
+		Symbol:        "BTC-USDT",
+		FirstSequence: first,
+		LastSequence:  last,
+		Bids:          []OrderBookChange{{Price: decimal.NewFromFloat(price), Quantity: decimal.NewFromInt(1)}},
+	}
+}
+
+func TestClient_updateOrderBook(t *testing.T) {
+	setup := func(t *testing.T) (*Client, *fakeOrderBookExchange, *fakeUseCases) {
+		e := &fakeOrderBookExchange{snapshots: make(chan entities.OrderBook, 2)}
+		u := &fakeUseCases{orderBook: make(chan entities.OrderBook, 10)}
+
+		c, err := NewClient(Config{Exchange: e, TradingPairs: []string{"BTC/USDT"}, UseCases: u})
+		require.NoError(t, err)
+
+		c.orderBooks = make(map[string]*localOrderBook)
+		c.pendingBooks = make(map[string]*pendingOrderBook)
+
+		return c, e, u
+	}
+
+	// waitForSnapshot waits until the snapshot has been fetched, so the next update picks it up.
+	waitForSnapshot := func(t *testing.T, c *Client) {
+		require.Eventually(t, func() bool {
+			c.booksMu.Lock()
+			defer c.booksMu.Unlock()
+			return len(c.pendingBooks["BTC-USDT"].snapshot) == 1
+		}, time.Second, time.Millisecond)
+	}
+
+	t.Run("replays updates buffered while fetching the snapshot", func(t *testing.T) {
+		c, e, u := setup(t)
+		ctx := context.Background()
+
+		c.updateOrderBook(ctx, bidUpdate(8, 10, 100))
+		c.updateOrderBook(ctx, bidUpdate(11, 12, 101))
+
+		e.snapshots <- entities.OrderBook{Sequence: 10}
+		waitForSnapshot(t, c)
+
+		c.updateOrderBook(ctx, bidUpdate(13, 14, 102))
+
+		ob := <-u.orderBook
+		require.Equal(t, int64(14), ob.Sequence)
+		require.Equal(t, []entities.OrderBookLevel{
+			{Price: decimal.NewFromFloat(102), Quantity: decimal.NewFromInt(1)},
+			{Price: decimal.NewFromFloat(101), Quantity: decimal.NewFromInt(1)},
+		}, ob.Bids)
+	})
+
+	t.Run("fetches a newer snapshot if it's older than the buffered updates", func(t *testing.T) {
+		c, e, u := setup(t)
+		ctx := context.Background()
+
+		c.updateOrderBook(ctx, bidUpdate(11, 12, 101))
+
+		e.snapshots <- entities.OrderBook{Sequence: 5}
+		waitForSnapshot(t, c)
+		c.updateOrderBook(ctx, bidUpdate(13, 14, 102))
+		require.Empty(t, u.orderBook)
+
+		e.snapshots <- entities.OrderBook{Sequence: 12}
+		waitForSnapshot(t, c)
+		c.updateOrderBook(ctx, bidUpdate(15, 16, 103))
+
+		ob := <-u.orderBook
+		require.Equal(t, int64(16), ob.Sequence)
+		require.Len(t, ob.Bids, 2)
+	})
+
+	t.Run("ignores changes past the deepest level of a partial snapshot", func(t *testing.T) {
+		c, e, u := setup(t)
+		e.depth = 2
+		ctx := context.Background()
+
+		c.updateOrderBook(ctx, bidUpdate(11, 11, 97))
+
+		e.snapshots <- entities.OrderBook{
+			Sequence: 10,
+			Bids:     []entities.OrderBookLevel{level(100), level(99)},
+			Asks:     []entities.OrderBookLevel{level(101)},
+		}
+		waitForSnapshot(t, c)
+		c.updateOrderBook(ctx, bidUpdate(12, 12, 99.5))
+
+		ob := <-u.orderBook
+		require.Equal(t, []entities.OrderBookLevel{level(100), level(99.5), level(99)}, ob.Bids)
+
+		// Every known bid has gone
+		c.updateOrderBook(ctx, OrderBookUpdate{
+			Symbol:        "BTC-USDT",
+			FirstSequence: 13,
+			LastSequence:  13,
+			Bids: []OrderBookChange{
+				{Price: decimal.NewFromFloat(100)},
+				{Price: decimal.NewFromFloat(99.5)},
+				{Price: decimal.NewFromFloat(99)},
+			},
+		})
+		require.Empty(t, u.orderBook)
+
+		c.booksMu.Lock()
+		defer c.booksMu.Unlock()
+		require.NotContains(t, c.orderBooks, "BTC-USDT")
+	})
+}
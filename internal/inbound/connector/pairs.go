@@ -0,0 +1,148 @@
+package connector
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+)
+
+// SetTradingPairs subscribes to pairs that aren't already subscribed and unsubscribes from pairs no longer wanted.
+// If connected, this is done on the live connection, so updates for the other pairs aren't interrupted by a reconnect.
+// Otherwise the pairs are subscribed when the connection is next started. If sending the messages fails, the pairs
+// are left as subscribed so far, so calling it again with the same pairs sends the rest.
+func (c *Client) SetTradingPairs(pairs []string) error {
+	symbolToPair, err := c.symbolsFor(pairs)
+	if err != nil {
+		return err
+	}
+
+	// A connection being set up subscribes to every pair first, so it's either included or changed on it after
+	c.subMu.Lock()
+	defer c.subMu.Unlock()
+
+	c.pairsMu.RLock()
+	previous := c.symbolToPair
+	c.pairsMu.RUnlock()
+
+	var added, removed []string
+	for symbol := range symbolToPair {
+		if _, ok := previous[symbol]; !ok {
+			added = append(added, symbol)
+		}
+	}
+	for symbol := range previous {
+		if _, ok := symbolToPair[symbol]; !ok {
+			removed = append(removed, symbol)
+		}
+	}
+
+	if !c.connected.Load() {
+		c.setSymbols(symbolToPair, removed)
+		return nil
+	}
+
+	if len(added)+len(removed) == 0 {
+		return nil
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	// Known before they're subscribed, so their first updates aren't dropped
+	subscribed := maps.Clone(previous)
+	for _, symbol := range added {
+		subscribed[symbol] = symbolToPair[symbol]
+	}
+	c.setSymbols(subscribed, nil)
+
+	if err = c.sendSubscriptions(c.exchange.SubscribeMessages, added); err != nil {
+		c.setSymbols(previous, nil)
+		return fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	if err = c.sendSubscriptions(c.exchange.UnsubscribeMessages, removed); err != nil {
+		// Still subscribed to the removed pairs, so they're unsubscribed from on the next call
+		return fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+
+	c.setSymbols(symbolToPair, removed)
+
+	c.log.Info().Strs("subscribed", added).Strs("unsubscribed", removed).Msg("Changed symbols")
+
+	return nil
+}
+
+// setSymbols replaces the subscribed symbols, dropping the order books of the removed ones.
+func (c *Client) setSymbols(symbolToPair map[string]string, removed []string) {
+	c.pairsMu.Lock()
+	c.symbolToPair = symbolToPair
+	c.pairsMu.Unlock()
+
+	// Synced again from a new snapshot if the pair is added back
+	c.booksMu.Lock()
+	for _, symbol := range removed {
+		delete(c.orderBooks, symbol)
+		delete(c.pendingBooks, symbol)
+	}
+	c.booksMu.Unlock()
+}
+
+// sendSubscriptions writes the messages returned by newMessages for the symbols, if there are any.
+func (c *Client) sendSubscriptions(newMessages func(symbols []string) ([][]byte, error), symbols []string) error {
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	msgs, err := newMessages(symbols)
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range msgs {
+		if err = c.write(msg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// symbolsFor returns the exchange symbol for each trading pair, e.g. "BTC/USDT" --> "BTCUSDT". Pairs are mapped to
+// their canonical form, e.g. "XBT/USD" --> "BTC/USD", so markets are comparable across exchanges.
+func (c *Client) symbolsFor(pairs []string) (map[string]string, error) {
+	symbolToPair := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		p, err := entities.ParsePair(pair)
+		if err != nil {
+			return nil, err
+		}
+
+		symbolToPair[c.exchange.Symbol(p)] = p.String()
+	}
+
+	return symbolToPair, nil
+}
+
+// pair returns the trading pair for an exchange symbol, if it's subscribed.
+func (c *Client) pair(symbol string) (string, bool) {
+	c.pairsMu.RLock()
+	defer c.pairsMu.RUnlock()
+
+	pair, ok := c.symbolToPair[symbol]
+	return pair, ok
+}
+
+// symbols returns every subscribed exchange symbol.
+func (c *Client) symbols() []string {
+	c.pairsMu.RLock()
+	defer c.pairsMu.RUnlock()
+
+	symbols := make([]string, 0, len(c.symbolToPair))
+	for symbol := range c.symbolToPair {
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols
+}
@@ -0,0 +1,105 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// writeWait is how long a control frame can take to send.
+const writeWait = 10 * time.Second
+
+// errNoData ends a connection that's still open but has stopped sending market data.
+var errNoData = errors.New("no market data received")
+
+// Watchdog is the policy for detecting connections that are open but no longer useful, e.g. half-open TCP
+// connections, or exchanges that stop sending data without closing the connection.
+type Watchdog struct {
+	DeadAfter   time.Duration // Feeds with no market data for this long, even after reconnecting, fail CheckAlive, defaults to 5m
+	ReadTimeout time.Duration // Connections with no frames for this long are closed, defaults to 30s
+	DataTimeout time.Duration // Connections with no tickers, order book updates or heartbeats for this long are closed, defaults to 1m
+}
+
+func newWatchdog(policy Watchdog) Watchdog {
+	if policy.DeadAfter == 0 {
+		// Default, long enough for a few reconnects at the maximum delay
+		policy.DeadAfter = 5 * time.Minute
+	}
+
+	if policy.ReadTimeout == 0 {
+		// Default
+		policy.ReadTimeout = 30 * time.Second
+	}
+
+	if policy.DataTimeout == 0 {
+		// Default
+		policy.DataTimeout = time.Minute
+	}
+
+	return policy
+}
+
+// handleControlFrames extends the read deadline whenever a ping or pong is received, replying to pings.
+func (c *Client) handleControlFrames(ctx context.Context, ws WebSocket) {
+	ws.SetPongHandler(func(string) error {
+		return c.extendReadDeadline(ctx, ws)
+	})
+
+	ws.SetPingHandler(func(data string) error {
+		if err := c.extendReadDeadline(ctx, ws); err != nil {
+			return err
+		}
+
+		err := ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(writeWait))
+
+		// As in the default ping handler, the connection is either closing or will fail on the next read
+		var netErr net.Error
+		if errors.Is(err, websocket.ErrCloseSent) || errors.As(err, &netErr) && netErr.Timeout() {
+			return nil
+		}
+
+		return err
+	})
+}
+
+// extendReadDeadline gives the connection another read timeout to receive a frame, unless the connection is ending.
+func (c *Client) extendReadDeadline(ctx context.Context, ws WebSocket) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	return ws.SetReadDeadline(time.Now().Add(c.watchdog.ReadTimeout))
+}
+
+// watch pings the exchange so the read deadline is extended while the connection is alive, and ends the connection
+// if no tickers, order book updates or heartbeats are received for the data timeout. Exchanges that only send a
+// ticker when a pair trades can be quiet for a while, which is why order book updates and heartbeats count too.
+// Without any symbols subscribed no data is expected, so the connection is left open. When the context is cancelled,
+// the read deadline is expired so listen stops blocking on the websocket.
+func (c *Client) watch(ctx context.Context, cancel context.CancelCauseFunc, ws WebSocket) {
+	// Ping often enough that a pong can arrive before the read deadline
+	pt := time.NewTicker(c.watchdog.ReadTimeout / 3)
+	defer pt.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = ws.SetReadDeadline(time.Now())
+			return
+		case <-pt.C:
+			since := time.Since(time.Unix(0, c.lastData.Load()))
+			if since > c.watchdog.DataTimeout && len(c.symbols()) > 0 {
+				c.log.Warn().Dur("since", since).Msg("No market data received, reconnecting")
+				cancel(errNoData)
+				continue
+			}
+
+			if err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.log.Debug().Err(err).Msg("Failed to send ping")
+			}
+		}
+	}
+}
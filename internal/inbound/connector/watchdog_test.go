@@ -0,0 +1,142 @@
+package connector
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWebSocket records the read deadline and pings sent by the watchdog.
+type fakeWebSocket struct {
+	WebSocket
+
+	mu           sync.Mutex
+	pings        int
+	readDeadline time.Time
+}
+
+func (ws *fakeWebSocket) SetReadDeadline(t time.Time) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	ws.readDeadline = t
+	return nil
+}
+
+func (ws *fakeWebSocket) WriteControl(int, []byte, time.Time) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	ws.pings++
+	return nil
+}
+
+// heartbeatExchange decodes every message as a heartbeat, like a feed that only sends tickers when a pair trades.
+type heartbeatExchange struct {
+	fakeExchange
+}
+
+func (e *heartbeatExchange) Decode([]byte) (Message, error) { return Message{Heartbeat: true}, nil }
+
+func TestClient_Watch(t *testing.T) {
+	t.Run("ends the connection if no market data is received", func(t *testing.T) {
+		c := &Client{
+			symbolToPair: map[string]string{"BTCUSDT": "BTC/USDT"},
+			watchdog:     newWatchdog(Watchdog{ReadTimeout: 30 * time.Millisecond, DataTimeout: 50 * time.Millisecond}),
+		}
+		c.lastData.Store(time.Now().UnixNano())
+
+		ws := &fakeWebSocket{}
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+
+		go c.watch(ctx, cancel, ws)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Fatal("watchdog didn't end the connection")
+		}
+
+		require.ErrorIs(t, context.Cause(ctx), errNoData)
+
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+		require.Positive(t, ws.pings)
+	})
+
+	t.Run("leaves a trade only feed open while it sends heartbeats", func(t *testing.T) {
+		c := &Client{
+			exchange:     &heartbeatExchange{},
+			symbolToPair: map[string]string{"BTC-USD": "BTC/USD"},
+			watchdog:     newWatchdog(Watchdog{ReadTimeout: 30 * time.Millisecond, DataTimeout: 50 * time.Millisecond}),
+		}
+		c.lastData.Store(time.Now().UnixNano())
+
+		ws := &fakeWebSocket{}
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+
+		go c.watch(ctx, cancel, ws)
+
+		// No trades, so no tickers, only heartbeats
+		deadline := time.After(200 * time.Millisecond)
+		for done := false; !done; {
+			select {
+			case <-ctx.Done():
+				t.Fatal("watchdog ended the connection")
+			case <-deadline:
+				done = true
+			case <-time.After(10 * time.Millisecond):
+				c.handleMessage(ctx, []byte("heartbeat"))
+			}
+		}
+
+		require.NoError(t, c.CheckAlive(ctx))
+	})
+
+	t.Run("leaves the connection open without any symbols", func(t *testing.T) {
+		c := &Client{
+			symbolToPair: map[string]string{},
+			watchdog:     newWatchdog(Watchdog{ReadTimeout: 30 * time.Millisecond, DataTimeout: 50 * time.Millisecond}),
+		}
+		c.lastData.Store(time.Now().UnixNano())
+
+		ws := &fakeWebSocket{}
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+
+		go c.watch(ctx, cancel, ws)
+
+		select {
+		case <-ctx.Done():
+			t.Fatal("watchdog ended the connection")
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+
+	t.Run("expires the read deadline when the context is cancelled", func(t *testing.T) {
+		c := &Client{watchdog: newWatchdog(Watchdog{})}
+		c.lastData.Store(time.Now().UnixNano())
+
+		ws := &fakeWebSocket{}
+		ctx, cancel := context.WithCancelCause(context.Background())
+
+		done := make(chan struct{})
+		go func() {
+			c.watch(ctx, cancel, ws)
+			close(done)
+		}()
+
+		cancel(nil)
+		<-done
+
+		ws.mu.Lock()
+		defer ws.mu.Unlock()
+		require.False(t, ws.readDeadline.After(time.Now()))
+		require.False(t, ws.readDeadline.IsZero())
+	})
+}
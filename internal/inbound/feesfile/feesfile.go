@@ -0,0 +1,69 @@
+// Package feesfile reads the fee tiers of each exchange from a YAML file, e.g. data/fees.yaml.
+package feesfile
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// Load returns the fees for each exchange, at the tier set in the file. Every exchange traded must have fees, as a
+// missing one would be treated as fee free and make its spreads look more profitable than they are.
+func Load(path string, exchanges []entities.Exchange) (entities.FeeSchedule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var cfg config
+	if err = yaml.NewDecoder(file).Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	fees := make(entities.FeeSchedule)
+
+	for _, ex := range cfg.Exchanges {
+		exFees := entities.ExchangeFees{
+			Tier:  ex.Tier,
+			Tiers: make(map[int]entities.Fee),
+		}
+
+		for _, t := range ex.Tiers {
+			exFees.Tiers[t.Tier] = entities.Fee{Maker: t.Maker, Taker: t.Taker}
+		}
+
+		if _, ok := exFees.Tiers[ex.Tier]; !ok {
+			return nil, fmt.Errorf("tier %d not specified for exchange %s", ex.Tier, ex.Name)
+		}
+
+		fees[entities.Exchange(ex.Name)] = exFees
+	}
+
+	for _, e := range exchanges {
+		if _, ok := fees[e]; !ok {
+			return nil, fmt.Errorf("fees not specified for exchange %s", e)
+		}
+	}
+
+	return fees, nil
+}
+
+type feeTier struct {
+	Tier  int             `yaml:"tier"`
+	Maker decimal.Decimal `yaml:"maker"`
+	Taker decimal.Decimal `yaml:"taker"`
+}
+
+type exchangeFees struct {
+	Name  string    `yaml:"name"`
+	Tier  int       `yaml:"tier"`
+	Tiers []feeTier `yaml:"tiers"`
+}
+
+type config struct {
+	Exchanges []exchangeFees `yaml:"exchanges"`
+}
@@ -0,0 +1,49 @@
+package feesfile_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/feesfile"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+const feesYAML = `exchanges:
+    - name: binance
+      tier: 1
+      tiers:
+        - tier: 0
+          maker: 0.001
+          taker: 0.001
+        - tier: 1
+          maker: 0.0009
+          taker: 0.001
+`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fees.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(feesYAML), 0o644))
+
+	t.Run("returns the fees at each exchange's tier", func(t *testing.T) {
+		fees, err := feesfile.Load(path, []entities.Exchange{entities.ExchangeBinance})
+		require.NoError(t, err)
+		require.Equal(t, 1, fees[entities.ExchangeBinance].Tier)
+		require.True(t, decimal.RequireFromString("0.0009").Equal(fees[entities.ExchangeBinance].Tiers[1].Maker))
+	})
+
+	t.Run("fails if an exchange traded has no fees", func(t *testing.T) {
+		_, err := feesfile.Load(path, []entities.Exchange{entities.ExchangeBinance, entities.ExchangeKuCoin})
+		require.ErrorContains(t, err, "fees not specified for exchange kucoin")
+	})
+
+	t.Run("fails if the tier isn't specified", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "fees.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("exchanges:\n    - name: binance\n      tier: 2\n      tiers: []\n"), 0o644))
+
+		_, err := feesfile.Load(path, nil)
+		require.ErrorContains(t, err, "tier 2 not specified for exchange binance")
+	})
+}
@@ -0,0 +1,62 @@
+package kraken
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+)
+
+// Bootstrap returns the websocket URL.
+// Kraken's public feed needs no authentication, and sends heartbeats to keep the connection alive.
+func (e *exchange) Bootstrap(_ context.Context) (connector.Connection, error) {
+	return connector.Connection{URL: e.websocketURL}, nil
+}
+
+// SubscribeMessages returns messages subscribing to the ticker and trade channels for every symbol.
+func (e *exchange) SubscribeMessages(symbols []string) ([][]byte, error) {
+	return subscriptionMessages("subscribe", symbols)
+}
+
+// UnsubscribeMessages returns messages unsubscribing from the ticker and trade channels for every symbol.
+func (e *exchange) UnsubscribeMessages(symbols []string) ([][]byte, error) {
+	return subscriptionMessages("unsubscribe", symbols)
+}
+
+// subscriptionMessages returns a request per channel, as each request names one.
+func subscriptionMessages(method string, symbols []string) ([][]byte, error) {
+	var msgs [][]byte
+	for _, channel := range []string{"ticker", "trade"} {
+		msg, err := json.Marshal(&subscriptionRequest{
+			Method: method,
+			Params: subscriptionParams{
+				Channel: channel,
+				Symbol:  symbols,
+			},
+			ReqID: 1,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		msgs = append(msgs, msg)
+	}
+
+	return msgs, nil
+}
+
+// KeepaliveMessage isn't needed, Kraken sends heartbeats.
+func (e *exchange) KeepaliveMessage() ([]byte, error) {
+	return nil, nil
+}
+
+type subscriptionRequest struct {
+	Method string             `json:"method"`
+	Params subscriptionParams `json:"params"`
+	ReqID  int                `json:"req_id"`
+}
+
+type subscriptionParams struct {
+	Channel string   `json:"channel"`
+	Symbol  []string `json:"symbol"`
+}
@@ -0,0 +1,131 @@
+package kraken
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+const AssetPairsRoute = "/0/public/AssetPairs"
+
+type MetadataConfig struct {
+	Hostname   string
+	HTTPClient http.Client
+}
+
+// Metadata gets what's listed on Kraken from its public REST API.
+type Metadata struct {
+	hostname   string
+	httpClient http.Client
+}
+
+func NewMetadata(cfg MetadataConfig) *Metadata {
+	return &Metadata{
+		hostname:   cfg.Hostname,
+		httpClient: cfg.HTTPClient,
+	}
+}
+
+func (m *Metadata) Exchange() entities.Exchange {
+	return entities.ExchangeKraken
+}
+
+// GetInstruments returns the order constraints of every asset pair on Kraken. Quantities are limited to lot_decimals
+// decimal places, so the step size is 10^-lot_decimals.
+func (m *Metadata) GetInstruments(ctx context.Context) ([]entities.Instrument, error) {
+	var pairs map[string]assetPair
+	if err := m.get(ctx, AssetPairsRoute, &pairs); err != nil {
+		return nil, fmt.Errorf("failed to get asset pairs: %w", err)
+	}
+
+	instruments := make([]entities.Instrument, 0, len(pairs))
+	for name, p := range pairs {
+		// Pairs that can't be traded over websockets have no wsname, e.g. dark pool pairs
+		if p.WSName == "" {
+			continue
+		}
+
+		// wsname uses the same symbols as the websocket, e.g. XBT/USD
+		pair, err := entities.ExchangeKraken.DecodeSymbol(p.WSName)
+		if err != nil {
+			log.Debug().Err(err).Str("pair", name).Msg("Skipping pair")
+			continue
+		}
+
+		instruments = append(instruments, entities.Instrument{
+			Exchange:    entities.ExchangeKraken,
+			TradingPair: pair.String(),
+			Status:      p.status(),
+			TickSize:    p.TickSize,
+			StepSize:    decimal.New(1, -p.LotDecimals),
+			MinQuantity: p.OrderMin,
+			MinNotional: p.CostMin,
+		})
+	}
+
+	return instruments, nil
+}
+
+// get decodes the result of a GET request to a route.
+func (m *Metadata) get(ctx context.Context, route string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.hostname+route, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-ok status: %s", resp.Status)
+	}
+
+	restResp := restResponse{Result: result}
+	if err = json.Unmarshal(body, &restResp); err != nil {
+		return err
+	}
+
+	if len(restResp.Error) > 0 {
+		return fmt.Errorf("received errors: %s", strings.Join(restResp.Error, ", "))
+	}
+
+	return nil
+}
+
+// restResponse wraps the result of every Kraken REST response.
+type restResponse struct {
+	Error  []string `json:"error"`
+	Result any      `json:"result"`
+}
+
+type assetPair struct {
+	WSName      string          `json:"wsname"` // e.g. XBT/USD
+	Status      string          `json:"status"` // e.g. online, cancel_only or post_only
+	TickSize    decimal.Decimal `json:"tick_size"`
+	LotDecimals int32           `json:"lot_decimals"`
+	OrderMin    decimal.Decimal `json:"ordermin"` // In the base asset
+	CostMin     decimal.Decimal `json:"costmin"`  // Min order value in the quote asset
+}
+
+func (p assetPair) status() entities.ListingStatus {
+	if p.Status == "online" {
+		return entities.ListingStatusTrading
+	}
+
+	return entities.ListingStatusHalted
+}
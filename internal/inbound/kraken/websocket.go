@@ -0,0 +1,169 @@
+package kraken
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+var errRequestFailed = errors.New("request failed")
+
+type timeNow func() time.Time
+
+// exchange implements the Kraken v2 websocket protocol for connector.Client.
+type exchange struct {
+	timeNow      timeNow
+	websocketURL string
+}
+
+type ExchangeConfig struct {
+	TimeNow      timeNow
+	WebsocketURL string
+}
+
+// NewExchange returns the Kraken websocket protocol, for a connector.Client.
+func NewExchange(cfg ExchangeConfig) connector.Exchange {
+	return &exchange{
+		timeNow:      cfg.TimeNow,
+		websocketURL: cfg.WebsocketURL,
+	}
+}
+
+func (e *exchange) Name() entities.Exchange {
+	return entities.ExchangeKraken
+}
+
+// Symbol returns the Kraken symbol for a trading pair, e.g. BTC/USD. The v2 API takes the same symbols we use.
+func (e *exchange) Symbol(pair entities.Pair) string {
+	return entities.ExchangeKraken.EncodeSymbol(pair)
+}
+
+// Decode decodes "ticker", "trade" and "heartbeat" messages. Other messages are ignored.
+func (e *exchange) Decode(p []byte) (connector.Message, error) {
+	var msg wsMessage
+	if err := json.Unmarshal(p, &msg); err != nil {
+		return connector.Message{}, err
+	}
+
+	// Kraken replies to each symbol in a request separately, naming it if it failed
+	if (msg.Method == "subscribe" || msg.Method == "unsubscribe") && !msg.Success {
+		return connector.Message{}, fmt.Errorf("%w: %s %s: %s", errRequestFailed, msg.Method, msg.Symbol, msg.Error)
+	}
+
+	switch msg.Channel {
+	case "heartbeat":
+		// Sent every second or so, so a connection to pairs that aren't trading is known to still be alive
+		return connector.Message{Heartbeat: true}, nil
+	case "ticker":
+		return e.decodeTickers(msg.Data)
+	case "trade":
+		// A snapshot repeats trades from before the subscription, which may already have been counted
+		if msg.Type == "snapshot" {
+			return connector.Message{}, nil
+		}
+		return e.decodeTrades(msg.Data)
+	default:
+		return connector.Message{}, nil
+	}
+}
+
+// decodeTickers decodes the data of a "ticker" message.
+func (e *exchange) decodeTickers(data json.RawMessage) (connector.Message, error) {
+	var ts []ticker
+	if err := json.Unmarshal(data, &ts); err != nil {
+		return connector.Message{}, err
+	}
+
+	tickers := make([]connector.Ticker, 0, len(ts))
+	for _, t := range ts {
+		// Older feeds can send legacy asset codes, e.g. XBT/USD
+		// One symbol we can't map shouldn't lose the other pairs in the message
+		pair, err := entities.ExchangeKraken.DecodeSymbol(t.Symbol)
+		if err != nil {
+			log.Warn().Err(err).Str("symbol", t.Symbol).Msg("Skipping Kraken ticker")
+			continue
+		}
+
+		// Only newer versions of the feed include a timestamp
+		timestamp := t.Timestamp
+		if timestamp.IsZero() {
+			timestamp = e.timeNow()
+		}
+
+		tickers = append(tickers, connector.Ticker{
+			Symbol: e.Symbol(pair),
+			Market: entities.Market{
+				BestBuyPrice:    decimal.NewFromFloat(t.Bid),
+				BestSellPrice:   decimal.NewFromFloat(t.Ask),
+				LastTradedPrice: decimal.NewFromFloat(t.Last),
+				Timestamp:       timestamp,
+				Volume24hr:      t.Volume * t.VWAP, // Kraken reports volume in the base asset
+			},
+		})
+	}
+
+	return connector.Message{Tickers: tickers}, nil
+}
+
+// decodeTrades decodes the data of a "trade" message, oldest trade first.
+func (e *exchange) decodeTrades(data json.RawMessage) (connector.Message, error) {
+	var ts []trade
+	if err := json.Unmarshal(data, &ts); err != nil {
+		return connector.Message{}, err
+	}
+
+	trades := make([]connector.Trade, 0, len(ts))
+	for _, t := range ts {
+		pair, err := entities.ExchangeKraken.DecodeSymbol(t.Symbol)
+		if err != nil {
+			log.Warn().Err(err).Str("symbol", t.Symbol).Msg("Skipping Kraken trade")
+			continue
+		}
+
+		trades = append(trades, connector.Trade{
+			Symbol: e.Symbol(pair),
+			Trade: entities.Trade{
+				ID:        t.ID,
+				Price:     decimal.NewFromFloat(t.Price),
+				Quantity:  decimal.NewFromFloat(t.Quantity),
+				Timestamp: t.Timestamp,
+			},
+		})
+	}
+
+	return connector.Message{Trades: trades}, nil
+}
+
+type wsMessage struct {
+	Channel string          `json:"channel"`
+	Type    string          `json:"type"` // "snapshot" or "update"
+	Data    json.RawMessage `json:"data"`
+	Method  string          `json:"method"` // Set on replies to requests
+	Success bool            `json:"success"`
+	Error   string          `json:"error"`  // Set on failed requests
+	Symbol  string          `json:"symbol"` // Set on failed requests, the symbol requested
+}
+
+type ticker struct {
+	Symbol    string    `json:"symbol"`
+	Bid       float64   `json:"bid"`
+	Ask       float64   `json:"ask"`
+	Last      float64   `json:"last"`
+	Volume    float64   `json:"volume"`
+	VWAP      float64   `json:"vwap"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+type trade struct {
+	Symbol    string    `json:"symbol"`
+	ID        int64     `json:"trade_id"`
+	Price     float64   `json:"price"`
+	Quantity  float64   `json:"qty"`
+	Timestamp time.Time `json:"timestamp"`
+}
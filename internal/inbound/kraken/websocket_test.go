@@ -0,0 +1,58 @@
+package kraken
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchange_Decode(t *testing.T) {
+	e := &exchange{timeNow: func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }}
+
+	t.Run("skips the symbols it can't decode and keeps the rest of the batch", func(t *testing.T) {
+		msg, err := e.Decode([]byte(`{"channel":"ticker","type":"update","data":[` +
+			`{"symbol":"XBT/USD","bid":100,"ask":101,"last":100.5,"volume":10,"vwap":100},` +
+			`{"symbol":"BTCUSD","bid":1,"ask":2,"last":1.5,"volume":10,"vwap":1},` +
+			`{"symbol":"ETH/EUR","bid":10,"ask":11,"last":10.5,"volume":10,"vwap":10}]}`))
+		require.NoError(t, err)
+		require.Len(t, msg.Tickers, 2)
+
+		// Legacy asset codes are decoded to ours
+		require.Equal(t, "BTC/USD", msg.Tickers[0].Symbol)
+		require.True(t, decimal.NewFromInt(100).Equal(msg.Tickers[0].Market.BestBuyPrice))
+		require.Equal(t, "ETH/EUR", msg.Tickers[1].Symbol)
+	})
+
+	t.Run("decodes trades", func(t *testing.T) {
+		msg, err := e.Decode([]byte(`{"channel":"trade","type":"update","data":[` +
+			`{"symbol":"XBT/USD","side":"buy","price":100.5,"qty":0.25,"ord_type":"limit","trade_id":4665846,` +
+			`"timestamp":"2024-01-01T00:00:00.5Z"}]}`))
+		require.NoError(t, err)
+		require.Len(t, msg.Trades, 1)
+		require.Equal(t, "BTC/USD", msg.Trades[0].Symbol)
+		require.Equal(t, int64(4665846), msg.Trades[0].Trade.ID)
+		require.True(t, decimal.NewFromFloat(100.5).Equal(msg.Trades[0].Trade.Price))
+		require.True(t, decimal.NewFromFloat(0.25).Equal(msg.Trades[0].Trade.Quantity))
+	})
+
+	t.Run("ignores trade snapshots", func(t *testing.T) {
+		msg, err := e.Decode([]byte(`{"channel":"trade","type":"snapshot","data":[` +
+			`{"symbol":"BTC/USD","price":100.5,"qty":0.25,"trade_id":4665846,"timestamp":"2024-01-01T00:00:00.5Z"}]}`))
+		require.NoError(t, err)
+		require.Empty(t, msg.Trades)
+	})
+
+	t.Run("returns the error and symbol of a failed subscription", func(t *testing.T) {
+		_, err := e.Decode([]byte(`{"method":"subscribe","success":false,"symbol":"FOO/BAR","error":"Currency pair not supported FOO/BAR"}`))
+		require.ErrorIs(t, err, errRequestFailed)
+		require.ErrorContains(t, err, "subscribe FOO/BAR: Currency pair not supported FOO/BAR")
+	})
+
+	t.Run("decodes heartbeats", func(t *testing.T) {
+		msg, err := e.Decode([]byte(`{"channel":"heartbeat"}`))
+		require.NoError(t, err)
+		require.True(t, msg.Heartbeat)
+	})
+}
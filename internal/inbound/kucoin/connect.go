@@ -9,40 +9,32 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/gorilla/websocket"
-	"github.com/rs/zerolog/log"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
 )
 
-// init gets the websocket connection info, starts the connection, and subscribes to the market topics.
-func (c *WebsocketClient) init(ctx context.Context) error {
-	url, token, pingInterval, err := c.getWebsocketData(ctx)
+// Bootstrap gets the websocket endpoint, token and ping interval from KuCoin.
+func (e *exchange) Bootstrap(ctx context.Context) (connector.Connection, error) {
+	url, token, pingInterval, err := e.getWebsocketData(ctx)
 	if err != nil {
-		return err
+		return connector.Connection{}, err
 	}
 
-	ws, resp, err := websocket.DefaultDialer.Dial(fmt.Sprintf("%s?token=%s", url, token), nil)
-	if err != nil {
-		return fmt.Errorf("failed to dial websocket: %w", err)
-	}
-	resp.Body.Close()
-
-	c.ws = ws
-	c.pingInterval = pingInterval
-
-	go c.keepAlive(ctx)
-
-	return c.subscribe()
+	return connector.Connection{
+		URL: fmt.Sprintf("%s?token=%s", url, token),
+		// Ping a little before the interval, so the connection isn't closed by KuCoin
+		KeepaliveInterval: pingInterval - 500*time.Millisecond,
+	}, nil
 }
 
 // getWebsocketData calls KuCoin BulletPublic API to get a valid endpoint and token to connect to their websocket
 // It returns the endpoint, the token, the ping interval in milliseconds and an error, if any
-func (c *WebsocketClient) getWebsocketData(ctx context.Context) (string, string, time.Duration, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.hostname+BulletPublicRoute, nil)
+func (e *exchange) getWebsocketData(ctx context.Context) (string, string, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.hostname+BulletPublicRoute, nil)
 	if err != nil {
 		return "", "", 0, err
 	}
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := e.httpClient.Do(req)
 	if err != nil {
 		return "", "", 0, fmt.Errorf("failed to get websocket connection data: %w", err)
 	}
@@ -72,6 +64,10 @@ func (c *WebsocketClient) getWebsocketData(ctx context.Context) (string, string,
 
 const (
 	BulletPublicRoute = "/api/v1/bullet-public"
+
+	snapshotTopic = "/market/snapshot:"
+	level2Topic   = "/market/level2:"
+	matchTopic    = "/market/match:"
 )
 
 type bulletPublicResponse struct {
@@ -84,63 +80,46 @@ type bulletPublicResponse struct {
 	} `json:"data"`
 }
 
-// subscribe sends a message to the websocket connection subscribing to each trading pair's market topic.
-func (c *WebsocketClient) subscribe() error {
-	// Iterate over each trading pair and create a subscription message
-	for symbol, pair := range c.kucoinSymbolToPair {
-		topic := fmt.Sprintf("/market/snapshot:%s", symbol)
-
-		msg := subscriptionRequest{
-			wsRequest: wsRequest{
-				ID:   strconv.FormatInt(c.timeNow().UnixNano(), 10),
-				Type: "subscribe",
-			},
-			Topic:          topic,
-			PrivateChannel: false,
-			Response:       false,
-		}
-
-		m, err := json.Marshal(msg)
-		if err != nil {
-			log.Err(err).Msgf("Failed to marshall subscription message for trading pair: %s", pair)
-			return err
-		}
-
-		if err = c.ws.WriteMessage(websocket.TextMessage, m); err != nil {
-			log.Err(err).Msgf("Failed to subscribe to trading pair: %s", pair)
-			return err
-		}
-
-		log.Info().Msgf("Subscribed to topic: %s", topic)
-	}
+// SubscribeMessages returns a message subscribing to each symbol's market, level 2 and match topics.
+func (e *exchange) SubscribeMessages(symbols []string) ([][]byte, error) {
+	return e.subscriptionMessages("subscribe", symbols)
+}
 
-	return nil
+// UnsubscribeMessages returns a message unsubscribing from each symbol's market, level 2 and match topics.
+func (e *exchange) UnsubscribeMessages(symbols []string) ([][]byte, error) {
+	return e.subscriptionMessages("unsubscribe", symbols)
 }
 
-// ping sends a ping message to the websocket every pingInterval
-func (c *WebsocketClient) keepAlive(ctx context.Context) {
-	pt := time.NewTicker(c.pingInterval - 500*time.Millisecond)
-	defer pt.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Info().Msg("Context canceled, stopping ping")
-			return
-		case <-pt.C:
-			msg, err := json.Marshal(wsRequest{
-				ID:   strconv.FormatInt(c.timeNow().UnixNano(), 10),
-				Type: "ping",
+func (e *exchange) subscriptionMessages(typ string, symbols []string) ([][]byte, error) {
+	var msgs [][]byte
+	for _, symbol := range symbols {
+		for _, topic := range []string{snapshotTopic + symbol, level2Topic + symbol, matchTopic + symbol} {
+			m, err := json.Marshal(subscriptionRequest{
+				wsRequest: wsRequest{
+					ID:   strconv.FormatInt(e.timeNow().UnixNano(), 10),
+					Type: typ,
+				},
+				Topic:          topic,
+				PrivateChannel: false,
+				Response:       false,
 			})
 			if err != nil {
-				log.Err(err).Msg("Failed to marshall ping message")
+				return nil, fmt.Errorf("failed to marshal %s message for topic %s: %w", typ, topic, err)
 			}
 
-			if err = c.ws.WriteMessage(websocket.TextMessage, msg); err != nil {
-				log.Err(err).Msg("Failed to send ping message")
-			}
+			msgs = append(msgs, m)
 		}
 	}
+
+	return msgs, nil
+}
+
+// KeepaliveMessage returns a ping message.
+func (e *exchange) KeepaliveMessage() ([]byte, error) {
+	return json.Marshal(wsRequest{
+		ID:   strconv.FormatInt(e.timeNow().UnixNano(), 10),
+		Type: "ping",
+	})
 }
 
 type subscriptionRequest struct {
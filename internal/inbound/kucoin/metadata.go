@@ -0,0 +1,187 @@
+package kucoin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	SymbolsRoute    = "/api/v2/symbols"
+	AllTickersRoute = "/api/v1/market/allTickers"
+)
+
+type MetadataConfig struct {
+	Hostname   string
+	HTTPClient http.Client
+}
+
+// Metadata gets what's listed on KuCoin from its public REST API.
+type Metadata struct {
+	hostname   string
+	httpClient http.Client
+}
+
+func NewMetadata(cfg MetadataConfig) *Metadata {
+	return &Metadata{
+		hostname:   cfg.Hostname,
+		httpClient: cfg.HTTPClient,
+	}
+}
+
+func (m *Metadata) Exchange() entities.Exchange {
+	return entities.ExchangeKuCoin
+}
+
+// GetListings returns every spot symbol on KuCoin, with its 24hr volume in the quote asset.
+func (m *Metadata) GetListings(ctx context.Context) ([]entities.Listing, error) {
+	var symbols []symbolInfo
+	if err := m.get(ctx, SymbolsRoute, &symbols); err != nil {
+		return nil, fmt.Errorf("failed to get symbols: %w", err)
+	}
+
+	var tickers allTickers
+	if err := m.get(ctx, AllTickersRoute, &tickers); err != nil {
+		return nil, fmt.Errorf("failed to get tickers: %w", err)
+	}
+
+	volumes := make(map[string]float64, len(tickers.Ticker))
+	for _, t := range tickers.Ticker {
+		if t.VolValue == "" {
+			continue
+		}
+
+		v, err := strconv.ParseFloat(t.VolValue, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse volume of %s: %w", t.Symbol, err)
+		}
+		volumes[t.Symbol] = v
+	}
+
+	listings := make([]entities.Listing, 0, len(symbols))
+	for _, s := range symbols {
+		pair, err := entities.NewPair(s.BaseCurrency, s.QuoteCurrency)
+		if err != nil {
+			log.Debug().Err(err).Str("symbol", s.Symbol).Msg("Skipping symbol")
+			continue
+		}
+
+		listings = append(listings, entities.Listing{
+			Exchange:    entities.ExchangeKuCoin,
+			TradingPair: pair.String(),
+			Base:        pair.Base.String(),
+			Quote:       pair.Quote.String(),
+			Status:      s.status(),
+			Volume24hr:  volumes[s.Symbol],
+		})
+	}
+
+	return listings, nil
+}
+
+// GetInstruments returns the order constraints of every spot symbol on KuCoin.
+func (m *Metadata) GetInstruments(ctx context.Context) ([]entities.Instrument, error) {
+	var symbols []symbolInfo
+	if err := m.get(ctx, SymbolsRoute, &symbols); err != nil {
+		return nil, fmt.Errorf("failed to get symbols: %w", err)
+	}
+
+	instruments := make([]entities.Instrument, 0, len(symbols))
+	for _, s := range symbols {
+		pair, err := entities.NewPair(s.BaseCurrency, s.QuoteCurrency)
+		if err != nil {
+			log.Debug().Err(err).Str("symbol", s.Symbol).Msg("Skipping symbol")
+			continue
+		}
+
+		instruments = append(instruments, entities.Instrument{
+			Exchange:    entities.ExchangeKuCoin,
+			TradingPair: pair.String(),
+			Status:      s.status(),
+			TickSize:    s.PriceIncrement,
+			StepSize:    s.BaseIncrement,
+			MinQuantity: s.BaseMinSize,
+			MaxQuantity: s.BaseMaxSize,
+			MinNotional: s.MinFunds,
+		})
+	}
+
+	return instruments, nil
+}
+
+// get decodes the data of a GET request to a route.
+func (m *Metadata) get(ctx context.Context, route string, data any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.hostname+route, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-ok status: %d", resp.StatusCode)
+	}
+
+	restResp := restResponse{Data: data}
+	if err = json.Unmarshal(body, &restResp); err != nil {
+		return err
+	}
+
+	if restResp.Code != successCode {
+		return fmt.Errorf("error code %s", restResp.Code)
+	}
+
+	return nil
+}
+
+// successCode is the code of successful KuCoin REST responses.
+const successCode = "200000"
+
+// restResponse wraps the data of every KuCoin REST response.
+type restResponse struct {
+	Code string `json:"code"`
+	Data any    `json:"data"`
+}
+
+type symbolInfo struct {
+	Symbol        string `json:"symbol"`
+	BaseCurrency  string `json:"baseCurrency"`
+	QuoteCurrency string `json:"quoteCurrency"`
+	EnableTrading bool   `json:"enableTrading"`
+
+	PriceIncrement decimal.Decimal `json:"priceIncrement"`
+	BaseIncrement  decimal.Decimal `json:"baseIncrement"`
+	BaseMinSize    decimal.Decimal `json:"baseMinSize"`
+	BaseMaxSize    decimal.Decimal `json:"baseMaxSize"`
+	MinFunds       decimal.Decimal `json:"minFunds"` // Min order value in the quote currency, null for some symbols
+}
+
+func (s symbolInfo) status() entities.ListingStatus {
+	if s.EnableTrading {
+		return entities.ListingStatusTrading
+	}
+
+	return entities.ListingStatusHalted
+}
+
+type allTickers struct {
+	Ticker []struct {
+		Symbol   string `json:"symbol"`
+		VolValue string `json:"volValue"` // 24hr volume in the quote currency
+	} `json:"ticker"`
+}
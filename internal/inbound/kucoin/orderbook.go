@@ -0,0 +1,190 @@
+package kucoin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// Level2SnapshotRoute is the public partial order book. The full order book needs an API key.
+	Level2SnapshotRoute = "/api/v1/market/orderbook/level2_100"
+
+	// level2SnapshotDepth is the number of levels on each side of a snapshot.
+	level2SnapshotDepth = 100
+)
+
+// decodeLevel2Update decodes a "/market/level2" message. Each change carries its own sequence,
+// so changes already included in the order book are skipped when syncing from a snapshot.
+func decodeLevel2Update(msg wsResponse) (connector.OrderBookUpdate, error) {
+	var data level2Update
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		return connector.OrderBookUpdate{}, err
+	}
+
+	bids, err := parseChanges(data.Changes.Bids)
+	if err != nil {
+		return connector.OrderBookUpdate{}, fmt.Errorf("failed to parse bids: %w", err)
+	}
+
+	asks, err := parseChanges(data.Changes.Asks)
+	if err != nil {
+		return connector.OrderBookUpdate{}, fmt.Errorf("failed to parse asks: %w", err)
+	}
+
+	return connector.OrderBookUpdate{
+		Symbol:        strings.TrimPrefix(msg.Topic, level2Topic),
+		FirstSequence: data.SequenceStart,
+		LastSequence:  data.SequenceEnd,
+		Bids:          bids,
+		Asks:          asks,
+		Timestamp:     time.UnixMilli(data.Time),
+	}, nil
+}
+
+// parseChanges parses [price, size, sequence] changes. A price of 0 only moves the sequence on, so is skipped.
+func parseChanges(changes [][]string) ([]connector.OrderBookChange, error) {
+	parsed := make([]connector.OrderBookChange, 0, len(changes))
+
+	for _, ch := range changes {
+		if len(ch) < 3 {
+			return nil, fmt.Errorf("invalid change %v", ch)
+		}
+
+		sequence, err := strconv.ParseInt(ch[2], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		price, err := decimal.NewFromString(ch[0])
+		if err != nil {
+			return nil, err
+		}
+
+		if price.IsZero() {
+			continue
+		}
+
+		size, err := decimal.NewFromString(ch[1])
+		if err != nil {
+			return nil, err
+		}
+
+		parsed = append(parsed, connector.OrderBookChange{Price: price, Quantity: size, Sequence: sequence})
+	}
+
+	return parsed, nil
+}
+
+// SnapshotDepth returns the number of levels on each side of a snapshot. The "/market/level2" updates cover the full
+// order book, so the synced order book only covers the prices of the snapshot's top levels.
+func (e *exchange) SnapshotDepth() int {
+	return level2SnapshotDepth
+}
+
+// OrderBookSnapshot gets an order book snapshot of the top 100 levels for the given symbol from KuCoin.
+func (e *exchange) OrderBookSnapshot(ctx context.Context, symbol string) (entities.OrderBook, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.hostname+Level2SnapshotRoute, nil)
+	if err != nil {
+		return entities.OrderBook{}, err
+	}
+
+	q := req.URL.Query()
+	q.Add("symbol", symbol)
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return entities.OrderBook{}, fmt.Errorf("failed to get order book snapshot: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return entities.OrderBook{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return entities.OrderBook{}, fmt.Errorf("non-ok status: %d", resp.StatusCode)
+	}
+
+	snapshotResp := level2SnapshotResponse{}
+	if err = json.Unmarshal(body, &snapshotResp); err != nil {
+		return entities.OrderBook{}, err
+	}
+
+	sequence, err := strconv.ParseInt(snapshotResp.Data.Sequence, 10, 64)
+	if err != nil {
+		return entities.OrderBook{}, fmt.Errorf("failed to parse sequence: %w", err)
+	}
+
+	ob := entities.OrderBook{
+		Sequence:  sequence,
+		Timestamp: time.UnixMilli(snapshotResp.Data.Time),
+	}
+
+	for _, l := range snapshotResp.Data.Bids {
+		level, err := parseLevel(l)
+		if err != nil {
+			return entities.OrderBook{}, fmt.Errorf("failed to parse bids: %w", err)
+		}
+		ob.Bids = append(ob.Bids, level)
+	}
+
+	for _, l := range snapshotResp.Data.Asks {
+		level, err := parseLevel(l)
+		if err != nil {
+			return entities.OrderBook{}, fmt.Errorf("failed to parse asks: %w", err)
+		}
+		ob.Asks = append(ob.Asks, level)
+	}
+
+	return ob, nil
+}
+
+// parseLevel parses a [price, size] pair into an order book level.
+func parseLevel(l []string) (entities.OrderBookLevel, error) {
+	if len(l) < 2 {
+		return entities.OrderBookLevel{}, fmt.Errorf("invalid level %v", l)
+	}
+
+	price, err := decimal.NewFromString(l[0])
+	if err != nil {
+		return entities.OrderBookLevel{}, err
+	}
+
+	size, err := decimal.NewFromString(l[1])
+	if err != nil {
+		return entities.OrderBookLevel{}, err
+	}
+
+	return entities.OrderBookLevel{Price: price, Quantity: size}, nil
+}
+
+type level2SnapshotResponse struct {
+	Data struct {
+		Time     int64      `json:"time"`
+		Sequence string     `json:"sequence"`
+		Bids     [][]string `json:"bids"`
+		Asks     [][]string `json:"asks"`
+	} `json:"data"`
+}
+
+type level2Update struct {
+	Changes struct {
+		Asks [][]string `json:"asks"` // [price, size, sequence]
+		Bids [][]string `json:"bids"`
+	} `json:"changes"`
+	SequenceStart int64  `json:"sequenceStart"`
+	SequenceEnd   int64  `json:"sequenceEnd"`
+	Symbol        string `json:"symbol"`
+	Time          int64  `json:"time"`
+}
@@ -1,151 +1,139 @@
 package kucoin
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/gorilla/websocket"
 	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
-	"github.com/rs/zerolog/log"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
 	"github.com/shopspring/decimal"
 )
 
 type timeNow func() time.Time
 
-type WebSocket interface {
-	Close() error
-	ReadMessage() (messageType int, p []byte, err error)
-	WriteMessage(messageType int, data []byte) error
+// exchange implements the KuCoin websocket protocol for connector.Client.
+type exchange struct {
+	hostname   string
+	httpClient http.Client
+	timeNow    timeNow
 }
 
-type WebsocketClientConfig struct {
-	Hostname     string
-	HTTPClient   http.Client
-	TradingPairs []string // e.g. ["BTC/BUSD", "ETH/BUSD"]
-	UseCases     MarketUpdaterUseCases
-	TimeNow      timeNow
+type ExchangeConfig struct {
+	Hostname   string
+	HTTPClient http.Client
+	TimeNow    timeNow
 }
 
-type WebsocketClient struct {
-	kucoinSymbolToPair map[string]string // BASE-QUOTE --> BASE/QUOTE
-	hostname           string
-	httpClient         http.Client
-	timeNow            timeNow
-	useCases           MarketUpdaterUseCases
+// NewExchange returns the KuCoin websocket protocol, for a connector.Client.
+func NewExchange(cfg ExchangeConfig) connector.OrderBookExchange {
+	return &exchange{
+		hostname:   cfg.Hostname,
+		httpClient: cfg.HTTPClient,
+		timeNow:    cfg.TimeNow,
+	}
+}
+
+func (e *exchange) Name() entities.Exchange {
+	return entities.ExchangeKuCoin
+}
 
-	// Set when the websocket starts.
-	pingInterval time.Duration
-	ws           WebSocket
+// Symbol returns the KuCoin symbol for a trading pair, e.g. BTC-USDT.
+func (e *exchange) Symbol(pair entities.Pair) string {
+	return entities.ExchangeKuCoin.EncodeSymbol(pair)
 }
 
-// NewWebsocket creates a new KuCoin websocket client.
-func NewWebsocket(cfg WebsocketClientConfig) (*WebsocketClient, error) {
-	c := &WebsocketClient{
-		hostname:           cfg.Hostname,
-		httpClient:         cfg.HTTPClient,
-		kucoinSymbolToPair: make(map[string]string),
-		useCases:           cfg.UseCases,
-		timeNow:            cfg.TimeNow,
+// Decode decodes "/market/snapshot", "/market/level2" and "/market/match" messages. Other messages are ignored.
+func (e *exchange) Decode(p []byte) (connector.Message, error) {
+	var msg wsResponse
+	if err := json.Unmarshal(p, &msg); err != nil {
+		return connector.Message{}, err
 	}
 
-	for _, pair := range cfg.TradingPairs {
-		s := strings.Split(pair, "/")
-		if len(s) != 2 {
-			return nil, fmt.Errorf("invalid pair %s", pair)
+	switch {
+	case strings.HasPrefix(msg.Topic, snapshotTopic):
+		t, err := decodeSnapshot(msg)
+		if err != nil {
+			return connector.Message{}, err
+		}
+		return connector.Message{Tickers: []connector.Ticker{t}}, nil
+	case strings.HasPrefix(msg.Topic, level2Topic):
+		u, err := decodeLevel2Update(msg)
+		if err != nil {
+			return connector.Message{}, err
+		}
+		return connector.Message{OrderBookUpdates: []connector.OrderBookUpdate{u}}, nil
+	case strings.HasPrefix(msg.Topic, matchTopic):
+		t, err := decodeMatch(msg)
+		if err != nil {
+			return connector.Message{}, err
 		}
+		return connector.Message{Trades: []connector.Trade{t}}, nil
+	default:
+		return connector.Message{}, nil
+	}
+}
 
-		c.kucoinSymbolToPair[strings.ToUpper(fmt.Sprintf("%s-%s", s[0], s[1]))] = pair
+// decodeSnapshot decodes a "/market/snapshot" message.
+func decodeSnapshot(msg wsResponse) (connector.Ticker, error) {
+	var data snapshot
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		return connector.Ticker{}, err
 	}
 
-	return c, nil
+	return connector.Ticker{
+		// Extract the symbol part from the topic
+		Symbol: strings.TrimPrefix(msg.Topic, snapshotTopic),
+		Market: entities.Market{
+			BestBuyPrice:    decimal.NewFromFloat(data.Market.Buy),
+			BestSellPrice:   decimal.NewFromFloat(data.Market.Sell),
+			LastTradedPrice: decimal.NewFromFloat(data.Market.LastTradedPrice),
+			Timestamp:       time.UnixMilli(data.Market.Datetime),
+			Volume24hr:      data.Market.VolValue,
+		},
+	}, nil
 }
 
-// Run starts the websocket client and blocks until the context is cancelled.
-// Attempts to reconnect on any error.
-func (c *WebsocketClient) Run(ctx context.Context) error {
-	for {
-		select {
-		case <-ctx.Done():
-			log.Info().Msg("context canceled, stopping websocket run")
-			return ctx.Err()
-		default:
-		}
+// decodeMatch decodes a "/market/match" message, a trade of the symbol.
+func decodeMatch(msg wsResponse) (connector.Trade, error) {
+	var data match
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		return connector.Trade{}, err
+	}
 
-		if err := c.init(ctx); err != nil {
-			return fmt.Errorf("failed to init websocket: %w", err)
-		}
+	// Trade IDs aren't always numbers, so the symbol's sequence number orders trades instead
+	sequence, err := strconv.ParseInt(data.Sequence, 10, 64)
+	if err != nil {
+		return connector.Trade{}, fmt.Errorf("failed to parse data.Sequence: %w", err)
+	}
 
-		if err := c.listen(ctx); err != nil {
-			log.Err(err).Msg("Error while listening to websocket")
-		}
+	price, err := decimal.NewFromString(data.Price)
+	if err != nil {
+		return connector.Trade{}, fmt.Errorf("failed to parse data.Price: %w", err)
+	}
 
-		if err := c.ws.Close(); err != nil {
-			log.Err(err).Msg("Error closing WebSocket")
-		}
+	quantity, err := decimal.NewFromString(data.Size)
+	if err != nil {
+		return connector.Trade{}, fmt.Errorf("failed to parse data.Size: %w", err)
 	}
-}
 
-// listen to the websocket, updating markets when a message is received.
-// Returns an error if the context is cancelled.
-func (c *WebsocketClient) listen(ctx context.Context) error {
-	for {
-		select {
-		case <-ctx.Done():
-			log.Info().Msg("Context canceled, stopping websocket listener")
-			return ctx.Err()
-		default:
-			messageType, p, err := c.ws.ReadMessage()
-			if err != nil {
-				log.Err(err).Msg("Failed to read message")
-				return err
-			}
-
-			if messageType == websocket.PongMessage {
-				continue
-			}
-
-			var msg wsResponse
-			if err = json.Unmarshal(p, &msg); err != nil {
-				log.Err(err).Interface("msg", string(p)).Msg("Failed to unmarshal response")
-				continue
-			}
-
-			// Filter based on the "/market/snapshot" topic
-			if !strings.HasPrefix(msg.Topic, "/market/snapshot:") {
-				continue
-			}
-
-			// Extract the symbol part from the topic
-			symbol := strings.TrimPrefix(msg.Topic, "/market/snapshot:")
-
-			pair, ok := c.kucoinSymbolToPair[symbol]
-			if !ok {
-				log.Warn().Msgf("Received data for unknown symbol: %s", symbol)
-				continue
-			}
-
-			lastTradedPrice := decimal.NewFromFloat(msg.Data.Market.LastTradedPrice)
-			buyPrice := decimal.NewFromFloat(msg.Data.Market.Buy)
-			sellPrice := decimal.NewFromFloat(msg.Data.Market.Sell)
-
-			err = c.useCases.UpdateMarket(ctx, entities.Market{
-				TradingPair:     pair,
-				Exchange:        entities.ExchangeKuCoin,
-				BestBuyPrice:    buyPrice,
-				BestSellPrice:   sellPrice,
-				LastTradedPrice: lastTradedPrice,
-				Timestamp:       time.UnixMilli(msg.Data.Market.Datetime),
-				Volume24hr:      msg.Data.Market.VolValue,
-			})
-			if err != nil {
-				log.Err(err).Interface("msg", msg).Msg("Failed to update market")
-			}
-		}
+	nanos, err := strconv.ParseInt(data.Time, 10, 64)
+	if err != nil {
+		return connector.Trade{}, fmt.Errorf("failed to parse data.Time: %w", err)
 	}
+
+	return connector.Trade{
+		Symbol: strings.TrimPrefix(msg.Topic, matchTopic),
+		Trade: entities.Trade{
+			ID:        sequence,
+			Price:     price,
+			Quantity:  quantity,
+			Timestamp: time.Unix(0, nanos),
+		},
+	}, nil
 }
 
 type wsRequest struct {
@@ -154,10 +142,10 @@ type wsRequest struct {
 }
 
 type wsResponse struct {
-	Type    string   `json:"type"`
-	Topic   string   `json:"topic"`
-	Subject string   `json:"subject"`
-	Data    snapshot `json:"data"`
+	Type    string          `json:"type"`
+	Topic   string          `json:"topic"`
+	Subject string          `json:"subject"`
+	Data    json.RawMessage `json:"data"` // Depends on the topic
 }
 
 type snapshot struct {
@@ -173,3 +161,10 @@ type market struct {
 	Sell            float64 `json:"sell"`
 	VolValue        float64 `json:"volValue"`
 }
+
+type match struct {
+	Sequence string `json:"sequence"`
+	Price    string `json:"price"`
+	Size     string `json:"size"`
+	Time     string `json:"time"` // Nanoseconds
+}
@@ -0,0 +1,237 @@
+package monitoring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var (
+	errNotChecked   = errors.New("not checked yet")
+	errShuttingDown = errors.New("shutting down")
+)
+
+// CheckKind is what a failing check means for the process.
+type CheckKind int
+
+const (
+	// Informational checks are reported on their own, without affecting readiness, e.g. one exchange's feed on the
+	// server, which can still serve the others.
+	Informational CheckKind = iota
+	// Readiness checks failing means the process shouldn't be sent traffic, e.g. Redis is unreachable.
+	Readiness
+	// Liveness checks failing means the process should be restarted, e.g. an exchange feed has silently died.
+	// They affect readiness too.
+	Liveness
+)
+
+// HealthCheck returns an error if a dependency is unhealthy.
+type HealthCheck struct {
+	Name  string // e.g. "redis" or "websocket", also the gRPC health service it's reported as
+	Kind  CheckKind
+	Check func(ctx context.Context) error
+}
+
+// FreshnessChecks returns an informational check for each exchange that its markets are being updated. Only the
+// exchanges being run should be given, as the markets of the others are never updated.
+func FreshnessChecks(exchanges []entities.Exchange, check func(ctx context.Context, exchange entities.Exchange) error) []HealthCheck {
+	checks := make([]HealthCheck, 0, len(exchanges))
+	for _, e := range exchanges {
+		checks = append(checks, HealthCheck{
+			Name:  "freshness/" + e.String(),
+			Kind:  Informational,
+			Check: func(ctx context.Context) error { return check(ctx, e) },
+		})
+	}
+
+	return checks
+}
+
+type HealthConfig struct {
+	Checks   []HealthCheck
+	GRPC     *health.Server // Optional, set to each check's status, and to readiness as a whole for the "" service
+	Interval time.Duration  // How often the checks run, defaults to 5s
+	Timeout  time.Duration  // How long a check can take before it fails, defaults to 2s
+}
+
+// Health runs health checks in the background, serving their latest results.
+type Health struct {
+	checks   []HealthCheck
+	grpc     *health.Server
+	interval time.Duration
+	timeout  time.Duration
+
+	mu           sync.RWMutex
+	results      map[string]error // Check name --> latest result
+	shuttingDown bool
+}
+
+func NewHealth(cfg HealthConfig) *Health {
+	if cfg.Interval == 0 {
+		// Default
+		cfg.Interval = 5 * time.Second
+	}
+
+	if cfg.Timeout == 0 {
+		// Default
+		cfg.Timeout = 2 * time.Second
+	}
+
+	return &Health{
+		checks:   cfg.Checks,
+		grpc:     cfg.GRPC,
+		interval: cfg.Interval,
+		timeout:  cfg.Timeout,
+		results:  make(map[string]error),
+	}
+}
+
+// Run runs every check each interval until the context is cancelled.
+func (h *Health) Run(ctx context.Context) {
+	t := time.NewTicker(h.interval)
+	defer t.Stop()
+
+	for {
+		h.runChecks(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (h *Health) runChecks(ctx context.Context) {
+	results := make(map[string]error, len(h.checks))
+	for _, c := range h.checks {
+		checkCtx, cancel := context.WithTimeout(ctx, h.timeout)
+		results[c.Name] = c.Check(checkCtx)
+		cancel()
+
+		if err := results[c.Name]; err != nil {
+			log.Warn().Err(err).Str("check", c.Name).Msg("Health check failed")
+		}
+	}
+
+	h.mu.Lock()
+	h.results = results
+	h.mu.Unlock()
+
+	if h.grpc == nil {
+		return
+	}
+
+	for _, c := range h.checks {
+		h.grpc.SetServingStatus(c.Name, servingStatus(results[c.Name] == nil))
+	}
+	h.grpc.SetServingStatus("", servingStatus(h.healthy(Readiness)))
+}
+
+// Shutdown fails readiness from now on, so load balancers stop sending the process traffic before it stops serving.
+// Liveness is unaffected.
+func (h *Health) Shutdown() {
+	h.mu.Lock()
+	h.shuttingDown = true
+	h.mu.Unlock()
+
+	if h.grpc != nil {
+		h.grpc.Shutdown()
+	}
+}
+
+// healthy returns whether every check of at least the given kind passed. Checks that haven't run yet fail readiness,
+// but not liveness, so a process isn't restarted while it starts up.
+func (h *Health) healthy(kind CheckKind) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if h.shuttingDown && kind == Readiness {
+		return false
+	}
+
+	for _, c := range h.checks {
+		if c.Kind < kind {
+			continue
+		}
+
+		err, ok := h.results[c.Name]
+		if !ok && kind == Liveness {
+			continue
+		}
+		if !ok || err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LivenessHandler serves whether the process should be restarted, for /healthz.
+func (h *Health) LivenessHandler() http.Handler {
+	return h.handler(Liveness)
+}
+
+// ReadinessHandler serves whether the process should be sent traffic, for /readyz.
+func (h *Health) ReadinessHandler() http.Handler {
+	return h.handler(Readiness)
+}
+
+// handler responds 200 if every check of at least the given kind passed, or 503 if not, with every check's result.
+func (h *Health) handler(kind CheckKind) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		resp := healthResponse{Status: "ok", Checks: make(map[string]string, len(h.checks))}
+		status := http.StatusOK
+
+		if !h.healthy(kind) {
+			resp.Status = "unhealthy"
+			status = http.StatusServiceUnavailable
+		}
+
+		h.mu.RLock()
+		if h.shuttingDown && kind == Readiness {
+			resp.Status = errShuttingDown.Error()
+		}
+
+		for _, c := range h.checks {
+			err, ok := h.results[c.Name]
+			if !ok {
+				err = errNotChecked
+			}
+
+			resp.Checks[c.Name] = "ok"
+			if err != nil {
+				resp.Checks[c.Name] = err.Error()
+			}
+		}
+		h.mu.RUnlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			log.Err(err).Msg("Failed to write health response")
+		}
+	})
+}
+
+type healthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"` // Check name --> "ok" or why it failed
+}
+
+func servingStatus(ok bool) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if ok {
+		return grpc_health_v1.HealthCheckResponse_SERVING
+	}
+
+	return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+}
@@ -0,0 +1,114 @@
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+func TestHealth(t *testing.T) {
+	pass := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return errors.New("down") }
+
+	status := func(h http.Handler) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec.Code
+	}
+
+	grpcStatus := func(hs *health.Server, service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+		resp, err := hs.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	t.Run("is live but not ready before the checks have run", func(t *testing.T) {
+		h := NewHealth(HealthConfig{Checks: []HealthCheck{{Name: "feed", Kind: Liveness, Check: pass}}})
+
+		require.Equal(t, http.StatusOK, status(h.LivenessHandler()))
+		require.Equal(t, http.StatusServiceUnavailable, status(h.ReadinessHandler()))
+	})
+
+	t.Run("failing readiness checks don't fail liveness", func(t *testing.T) {
+		hs := health.NewServer()
+		h := NewHealth(HealthConfig{
+			Checks: []HealthCheck{
+				{Name: "redis", Kind: Readiness, Check: fail},
+				{Name: "feed", Kind: Liveness, Check: pass},
+			},
+			GRPC: hs,
+		})
+		h.runChecks(context.Background())
+
+		require.Equal(t, http.StatusOK, status(h.LivenessHandler()))
+		require.Equal(t, http.StatusServiceUnavailable, status(h.ReadinessHandler()))
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, grpcStatus(hs, ""))
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, grpcStatus(hs, "redis"))
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, grpcStatus(hs, "feed"))
+	})
+
+	t.Run("failing liveness checks fail readiness too", func(t *testing.T) {
+		h := NewHealth(HealthConfig{Checks: []HealthCheck{{Name: "feed", Kind: Liveness, Check: fail}}})
+		h.runChecks(context.Background())
+
+		require.Equal(t, http.StatusServiceUnavailable, status(h.LivenessHandler()))
+		require.Equal(t, http.StatusServiceUnavailable, status(h.ReadinessHandler()))
+	})
+
+	t.Run("failing informational checks are only reported on their own", func(t *testing.T) {
+		hs := health.NewServer()
+		h := NewHealth(HealthConfig{
+			Checks: []HealthCheck{{Name: "freshness/binance", Kind: Informational, Check: fail}},
+			GRPC:   hs,
+		})
+		h.runChecks(context.Background())
+
+		require.Equal(t, http.StatusOK, status(h.ReadinessHandler()))
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, grpcStatus(hs, ""))
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, grpcStatus(hs, "freshness/binance"))
+	})
+
+	t.Run("only checks the freshness of the exchanges given", func(t *testing.T) {
+		hs := health.NewServer()
+		h := NewHealth(HealthConfig{
+			// Only Binance is deployed, so nothing updates the other exchanges' markets
+			Checks: FreshnessChecks([]entities.Exchange{entities.ExchangeBinance}, func(_ context.Context, e entities.Exchange) error {
+				if e != entities.ExchangeBinance {
+					return errors.New("stale")
+				}
+				return nil
+			}),
+			GRPC: hs,
+		})
+		h.runChecks(context.Background())
+
+		require.Equal(t, http.StatusOK, status(h.ReadinessHandler()))
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, grpcStatus(hs, ""))
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, grpcStatus(hs, "freshness/binance"))
+
+		_, err := hs.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "freshness/kraken"})
+		require.Equal(t, codes.NotFound, grpcstatus.Code(err))
+	})
+
+	t.Run("fails readiness but not liveness once shutting down", func(t *testing.T) {
+		hs := health.NewServer()
+		h := NewHealth(HealthConfig{Checks: []HealthCheck{{Name: "feed", Kind: Liveness, Check: pass}}, GRPC: hs})
+		h.runChecks(context.Background())
+		require.Equal(t, http.StatusOK, status(h.ReadinessHandler()))
+
+		h.Shutdown()
+
+		require.Equal(t, http.StatusOK, status(h.LivenessHandler()))
+		require.Equal(t, http.StatusServiceUnavailable, status(h.ReadinessHandler()))
+		require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, grpcStatus(hs, ""))
+	})
+}
@@ -0,0 +1,81 @@
+// Package monitoring serves the HTTP endpoints used to monitor each binary, e.g. /metrics for Prometheus and /healthz
+// for Kubernetes, and sets up tracing.
+package monitoring
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rs/zerolog/log"
+)
+
+const shutdownTimeout = 5 * time.Second
+
+type Config struct {
+	Health        *Health // Optional, served on /healthz and /readyz
+	Port          int
+	ShutdownDelay time.Duration // How long /readyz fails for before the endpoints stop being served
+}
+
+type Server struct {
+	health        *Health
+	shutdownDelay time.Duration
+	srv           *http.Server
+}
+
+func NewServer(cfg Config) *Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	if cfg.Health != nil {
+		mux.Handle("/healthz", cfg.Health.LivenessHandler())
+		mux.Handle("/readyz", cfg.Health.ReadinessHandler())
+	}
+
+	return &Server{
+		health:        cfg.Health,
+		shutdownDelay: cfg.ShutdownDelay,
+		srv: &http.Server{
+			Addr:              fmt.Sprintf(":%d", cfg.Port),
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// Run serves the monitoring endpoints, running the health checks, and blocks until the context is cancelled.
+// Once cancelled, /readyz fails for the shutdown delay before the server stops.
+func (s *Server) Run(ctx context.Context) error {
+	if s.health != nil {
+		go s.health.Run(ctx)
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		if s.health != nil {
+			s.health.Shutdown()
+		}
+		time.Sleep(s.shutdownDelay)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := s.srv.Shutdown(shutdownCtx); err != nil {
+			log.Err(err).Msg("Failed to shut down monitoring server")
+		}
+	}()
+
+	log.Info().Str("address", s.srv.Addr).Msg("Starting monitoring server")
+
+	err := s.srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
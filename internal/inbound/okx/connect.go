@@ -0,0 +1,65 @@
+package okx
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+)
+
+// pingInterval is how often a "ping" is sent. OKX closes connections that are silent for 30 seconds.
+const pingInterval = 25 * time.Second
+
+// Bootstrap returns the websocket URL. OKX's public feed needs no authentication.
+func (e *exchange) Bootstrap(_ context.Context) (connector.Connection, error) {
+	return connector.Connection{
+		URL:               e.websocketURL,
+		KeepaliveInterval: pingInterval,
+	}, nil
+}
+
+// SubscribeMessages returns a message subscribing to the tickers and trades channels for every symbol.
+func (e *exchange) SubscribeMessages(symbols []string) ([][]byte, error) {
+	return subscriptionMessages("subscribe", symbols)
+}
+
+// UnsubscribeMessages returns a message unsubscribing from the tickers and trades channels for every symbol.
+func (e *exchange) UnsubscribeMessages(symbols []string) ([][]byte, error) {
+	return subscriptionMessages("unsubscribe", symbols)
+}
+
+func subscriptionMessages(op string, symbols []string) ([][]byte, error) {
+	args := make([]subscriptionArg, 0, 2*len(symbols))
+	for _, symbol := range symbols {
+		args = append(args,
+			subscriptionArg{Channel: "tickers", InstID: symbol},
+			subscriptionArg{Channel: "trades", InstID: symbol},
+		)
+	}
+
+	msg, err := json.Marshal(&subscriptionRequest{
+		Op:   op,
+		Args: args,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]byte{msg}, nil
+}
+
+// KeepaliveMessage returns a plain text "ping", which OKX answers with "pong".
+func (e *exchange) KeepaliveMessage() ([]byte, error) {
+	return []byte("ping"), nil
+}
+
+type subscriptionRequest struct {
+	Op   string            `json:"op"`
+	Args []subscriptionArg `json:"args"`
+}
+
+type subscriptionArg struct {
+	Channel string `json:"channel"`
+	InstID  string `json:"instId"`
+}
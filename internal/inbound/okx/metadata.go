@@ -0,0 +1,130 @@
+package okx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+const InstrumentsRoute = "/api/v5/public/instruments?instType=SPOT"
+
+type MetadataConfig struct {
+	Hostname   string
+	HTTPClient http.Client
+}
+
+// Metadata gets what's listed on OKX from its public REST API.
+type Metadata struct {
+	hostname   string
+	httpClient http.Client
+}
+
+func NewMetadata(cfg MetadataConfig) *Metadata {
+	return &Metadata{
+		hostname:   cfg.Hostname,
+		httpClient: cfg.HTTPClient,
+	}
+}
+
+func (m *Metadata) Exchange() entities.Exchange {
+	return entities.ExchangeOKX
+}
+
+// GetInstruments returns the order constraints of every spot instrument on OKX. OKX has no minimum order value, so
+// MinNotional isn't set.
+func (m *Metadata) GetInstruments(ctx context.Context) ([]entities.Instrument, error) {
+	var infos []instrumentInfo
+	if err := m.get(ctx, InstrumentsRoute, &infos); err != nil {
+		return nil, fmt.Errorf("failed to get instruments: %w", err)
+	}
+
+	instruments := make([]entities.Instrument, 0, len(infos))
+	for _, info := range infos {
+		pair, err := entities.NewPair(info.BaseCcy, info.QuoteCcy)
+		if err != nil {
+			log.Debug().Err(err).Str("instrument", info.InstID).Msg("Skipping instrument")
+			continue
+		}
+
+		instruments = append(instruments, entities.Instrument{
+			Exchange:    entities.ExchangeOKX,
+			TradingPair: pair.String(),
+			Status:      info.status(),
+			TickSize:    info.TickSz,
+			StepSize:    info.LotSz,
+			MinQuantity: info.MinSz,
+			MaxQuantity: info.MaxLmtSz,
+		})
+	}
+
+	return instruments, nil
+}
+
+// get decodes the data of a GET request to a route.
+func (m *Metadata) get(ctx context.Context, route string, data any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.hostname+route, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-ok status: %s", resp.Status)
+	}
+
+	restResp := restResponse{Data: data}
+	if err = json.Unmarshal(body, &restResp); err != nil {
+		return err
+	}
+
+	if restResp.Code != successCode {
+		return fmt.Errorf("error code %s: %s", restResp.Code, restResp.Msg)
+	}
+
+	return nil
+}
+
+// successCode is the code of successful OKX REST responses.
+const successCode = "0"
+
+// restResponse wraps the data of every OKX REST response.
+type restResponse struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+	Data any    `json:"data"`
+}
+
+type instrumentInfo struct {
+	InstID   string          `json:"instId"` // e.g. BTC-USDT
+	BaseCcy  string          `json:"baseCcy"`
+	QuoteCcy string          `json:"quoteCcy"`
+	State    string          `json:"state"` // e.g. live, suspend or preopen
+	TickSz   decimal.Decimal `json:"tickSz"`
+	LotSz    decimal.Decimal `json:"lotSz"`
+	MinSz    decimal.Decimal `json:"minSz"`
+	MaxLmtSz decimal.Decimal `json:"maxLmtSz"` // Max quantity of a limit order
+}
+
+func (i instrumentInfo) status() entities.ListingStatus {
+	if i.State == "live" {
+		return entities.ListingStatusTrading
+	}
+
+	return entities.ListingStatusHalted
+}
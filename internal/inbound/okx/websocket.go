@@ -0,0 +1,201 @@
+package okx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+	"github.com/shopspring/decimal"
+)
+
+// exchange implements the OKX v5 websocket protocol for connector.Client.
+type exchange struct {
+	websocketURL string
+}
+
+type ExchangeConfig struct {
+	WebsocketURL string
+}
+
+// NewExchange returns the OKX websocket protocol, for a connector.Client.
+func NewExchange(cfg ExchangeConfig) connector.Exchange {
+	return &exchange{websocketURL: cfg.WebsocketURL}
+}
+
+func (e *exchange) Name() entities.Exchange {
+	return entities.ExchangeOKX
+}
+
+// Symbol returns the OKX instrument ID for a trading pair, e.g. BTC-USDT.
+func (e *exchange) Symbol(pair entities.Pair) string {
+	return entities.ExchangeOKX.EncodeSymbol(pair)
+}
+
+// Decode decodes "tickers" and "trades" messages. Other messages are ignored.
+func (e *exchange) Decode(p []byte) (connector.Message, error) {
+	// Reply to a "ping"
+	if string(p) == "pong" {
+		return connector.Message{}, nil
+	}
+
+	var msg wsMessage
+	if err := json.Unmarshal(p, &msg); err != nil {
+		return connector.Message{}, err
+	}
+
+	if msg.Event == "error" {
+		return connector.Message{}, fmt.Errorf("received error from OKX: %s %s", msg.Code, msg.Msg)
+	}
+
+	// Other events, e.g. "subscribe", are replies to requests
+	if msg.Event != "" {
+		return connector.Message{}, nil
+	}
+
+	switch msg.Arg.Channel {
+	case "tickers":
+		return decodeTickers(msg.Data)
+	case "trades":
+		return decodeTrades(msg.Data)
+	default:
+		return connector.Message{}, nil
+	}
+}
+
+// decodeTickers decodes the data of a "tickers" message, skipping instruments with an empty side of the book.
+func decodeTickers(data json.RawMessage) (connector.Message, error) {
+	var ts []ticker
+	if err := json.Unmarshal(data, &ts); err != nil {
+		return connector.Message{}, err
+	}
+
+	tickers := make([]connector.Ticker, 0, len(ts))
+	for _, t := range ts {
+		lastPrice, err := decimal.NewFromString(t.Last)
+		if err != nil {
+			return connector.Message{}, fmt.Errorf("failed to parse ticker.Last: %w", err)
+		}
+
+		bestBuyPrice, err := parsePrice(t.BidPx)
+		if err != nil {
+			return connector.Message{}, fmt.Errorf("failed to parse ticker.BidPx: %w", err)
+		}
+
+		bestSellPrice, err := parsePrice(t.AskPx)
+		if err != nil {
+			return connector.Message{}, fmt.Errorf("failed to parse ticker.AskPx: %w", err)
+		}
+
+		// One side of the book is empty, so there's no market to quote
+		if bestBuyPrice.IsZero() || bestSellPrice.IsZero() {
+			continue
+		}
+
+		// For spot, volCcy24h is in the quote asset
+		volume, err := strconv.ParseFloat(t.VolCcy24h, 64)
+		if err != nil {
+			return connector.Message{}, fmt.Errorf("failed to parse ticker.VolCcy24h: %w", err)
+		}
+
+		timestamp, err := strconv.ParseInt(t.Timestamp, 10, 64)
+		if err != nil {
+			return connector.Message{}, fmt.Errorf("failed to parse ticker.Timestamp: %w", err)
+		}
+
+		tickers = append(tickers, connector.Ticker{
+			Symbol: t.InstID,
+			Market: entities.Market{
+				BestBuyPrice:    bestBuyPrice,
+				BestSellPrice:   bestSellPrice,
+				LastTradedPrice: lastPrice,
+				Timestamp:       time.UnixMilli(timestamp),
+				Volume24hr:      volume,
+			},
+		})
+	}
+
+	return connector.Message{Tickers: tickers}, nil
+}
+
+// decodeTrades decodes the data of a "trades" message. Each is the trades of one taker order at one price.
+func decodeTrades(data json.RawMessage) (connector.Message, error) {
+	var ts []trade
+	if err := json.Unmarshal(data, &ts); err != nil {
+		return connector.Message{}, err
+	}
+
+	trades := make([]connector.Trade, 0, len(ts))
+	for _, t := range ts {
+		id, err := strconv.ParseInt(t.TradeID, 10, 64)
+		if err != nil {
+			return connector.Message{}, fmt.Errorf("failed to parse trade.TradeID: %w", err)
+		}
+
+		price, err := decimal.NewFromString(t.Px)
+		if err != nil {
+			return connector.Message{}, fmt.Errorf("failed to parse trade.Px: %w", err)
+		}
+
+		quantity, err := decimal.NewFromString(t.Sz)
+		if err != nil {
+			return connector.Message{}, fmt.Errorf("failed to parse trade.Sz: %w", err)
+		}
+
+		timestamp, err := strconv.ParseInt(t.Timestamp, 10, 64)
+		if err != nil {
+			return connector.Message{}, fmt.Errorf("failed to parse trade.Timestamp: %w", err)
+		}
+
+		trades = append(trades, connector.Trade{
+			Symbol: t.InstID,
+			Trade: entities.Trade{
+				ID:        id,
+				Price:     price,
+				Quantity:  quantity,
+				Timestamp: time.UnixMilli(timestamp),
+			},
+		})
+	}
+
+	return connector.Message{Trades: trades}, nil
+}
+
+// parsePrice parses a price, which is empty when there are no orders on that side of the book.
+func parsePrice(s string) (decimal.Decimal, error) {
+	if s == "" {
+		return decimal.Zero, nil
+	}
+
+	return decimal.NewFromString(s)
+}
+
+type wsMessage struct {
+	Event string `json:"event"` // Set on replies to requests
+	Code  string `json:"code"`
+	Msg   string `json:"msg"`
+	Arg   struct {
+		Channel string `json:"channel"`
+		InstID  string `json:"instId"`
+	} `json:"arg"`
+	Data json.RawMessage `json:"data"` // Depends on the channel
+}
+
+type ticker struct {
+	InstID    string `json:"instId"`
+	Last      string `json:"last"`
+	BidPx     string `json:"bidPx"`
+	AskPx     string `json:"askPx"`
+	VolCcy24h string `json:"volCcy24h"`
+	Timestamp string `json:"ts"` // Unix milliseconds
+}
+
+type trade struct {
+	InstID    string `json:"instId"`
+	TradeID   string `json:"tradeId"`
+	Px        string `json:"px"`
+	Sz        string `json:"sz"`
+	Timestamp string `json:"ts"` // Unix milliseconds
+}
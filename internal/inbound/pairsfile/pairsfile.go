@@ -0,0 +1,252 @@
+// Package pairsfile reads the trading pairs of each exchange from a YAML file, e.g. data/trading_pairs.yaml, and
+// watches it so pairs can be added and removed without restarting.
+package pairsfile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Load returns the trading pairs listed for an exchange, or for every exchange if e is empty, in canonical form, e.g.
+// BTC/USD for XBT/USD, without duplicates. Fails if any of them isn't a valid pair, e.g. "BTCUSDT".
+func Load(path string, e entities.Exchange) ([]string, error) {
+	cfg, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs []string
+	found := e == ""
+
+	for _, ex := range cfg.Exchanges {
+		if e != "" && ex.Name != e.String() {
+			continue
+		}
+		found = true
+
+		for _, pair := range ex.Pairs {
+			p, err := entities.ParsePair(pair)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s for %s: %w", path, ex.Name, err)
+			}
+
+			if !slices.Contains(pairs, p.String()) {
+				pairs = append(pairs, p.String())
+			}
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("exchange %s not specified", e)
+	}
+
+	return pairs, nil
+}
+
+// Exchanges returns the exchanges listed in the file, in the order they're listed.
+func Exchanges(path string) ([]entities.Exchange, error) {
+	cfg, err := read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	exchanges := make([]entities.Exchange, 0, len(cfg.Exchanges))
+	for _, ex := range cfg.Exchanges {
+		exchanges = append(exchanges, entities.Exchange(ex.Name))
+	}
+
+	return exchanges, nil
+}
+
+func read(path string) (config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return config{}, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var cfg config
+	if err = yaml.NewDecoder(file).Decode(&cfg); err != nil {
+		return config{}, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Update replaces the trading pairs of the given exchanges, keeping the pairs of every other exchange. Exchanges not in
+// the file are added. The file is replaced in one step so a Watcher never reads it half written.
+func Update(path string, pairs map[entities.Exchange][]string) error {
+	var cfg config
+
+	file, err := os.Open(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	default:
+		err = yaml.NewDecoder(file).Decode(&cfg)
+		file.Close()
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+	}
+
+	for i, ex := range cfg.Exchanges {
+		if p, ok := pairs[entities.Exchange(ex.Name)]; ok {
+			cfg.Exchanges[i].Pairs = p
+		}
+	}
+
+	for _, e := range entities.Exchanges {
+		p, ok := pairs[e]
+		if ok && !slices.ContainsFunc(cfg.Exchanges, func(ex exchange) bool { return ex.Name == e.String() }) {
+			cfg.Exchanges = append(cfg.Exchanges, exchange{Name: e.String(), Pairs: p})
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	enc := yaml.NewEncoder(tmp)
+	enc.SetIndent(4)
+	err = errors.Join(enc.Encode(cfg), enc.Close(), tmp.Chmod(0o644), tmp.Close())
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp.Name(), err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return nil
+}
+
+type exchange struct {
+	Name  string   `yaml:"name"`
+	Pairs []string `yaml:"pairs"`
+}
+
+type config struct {
+	Exchanges []exchange `yaml:"exchanges"`
+}
+
+// Updater changes the trading pairs of a running exchange feed, e.g. connector.Client, or of anything else using them,
+// e.g. usecases.Market.
+type Updater interface {
+	SetTradingPairs(pairs []string) error
+}
+
+type WatcherConfig struct {
+	Exchange entities.Exchange // Empty to watch every exchange's pairs
+	Interval time.Duration     // How often the file is read, defaults to 10s
+	Path     string
+	Updater  Updater
+}
+
+// Watcher reads the file every interval, passing an exchange's pairs to its updater when they change.
+type Watcher struct {
+	exchange entities.Exchange
+	interval time.Duration
+	path     string
+	updater  Updater
+}
+
+func NewWatcher(cfg WatcherConfig) *Watcher {
+	if cfg.Interval == 0 {
+		// Default
+		cfg.Interval = 10 * time.Second
+	}
+
+	return &Watcher{
+		exchange: cfg.Exchange,
+		interval: cfg.Interval,
+		path:     cfg.Path,
+		updater:  cfg.Updater,
+	}
+}
+
+// Run watches the file and blocks until the context is cancelled. The pairs in the file when it starts are assumed to
+// be the ones the updater was created with. The file is only read again once it's changed, and if it can't be read,
+// e.g. while it's being edited, the pairs are left as they are. If the updater fails to set the pairs, it's given
+// them again every interval until it succeeds.
+func (w *Watcher) Run(ctx context.Context) error {
+	read, err := os.Stat(w.path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", w.path, err)
+	}
+
+	current, err := Load(w.path, w.exchange)
+	if err != nil {
+		return err
+	}
+	wanted := current
+
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+
+		info, err := os.Stat(w.path)
+		switch {
+		case err != nil:
+			if read != nil {
+				log.Warn().Err(err).Msg("Failed to read trading pairs, keeping the current pairs")
+			}
+			// Read again once it's back
+			read = nil
+		case changed(read, info):
+			read = info
+
+			pairs, err := Load(w.path, w.exchange)
+			if err != nil {
+				log.Warn().Err(err).Msg("Failed to read trading pairs, keeping the current pairs")
+				break
+			}
+			wanted = pairs
+		}
+
+		if sameSet(wanted, current) {
+			continue
+		}
+
+		if err = w.updater.SetTradingPairs(wanted); err != nil {
+			// Retried on the next tick, as the pairs still differ
+			log.Err(err).Str("exchange", w.exchange.String()).Strs("pairs", wanted).Msg("Failed to set trading pairs")
+			continue
+		}
+
+		current = wanted
+	}
+}
+
+// changed returns whether the file has changed since it was last read, or wasn't read.
+func changed(read, info os.FileInfo) bool {
+	return read == nil || !info.ModTime().Equal(read.ModTime()) || info.Size() != read.Size()
+}
+
+// sameSet returns whether a and b hold the same pairs, ignoring order.
+func sameSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+
+	return slices.Equal(a, b)
+}
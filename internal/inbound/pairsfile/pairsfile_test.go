@@ -0,0 +1,314 @@
+package pairsfile_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/pairsfile"
+	"github.com/stretchr/testify/require"
+)
+
+const pairsYAML = `exchanges:
+    - name: binance
+      pairs:
+        - BTC/USDT
+        - ETH/USDT
+    - name: kucoin
+      pairs:
+        - BTC/USDT
+`
+
+// fakeUpdater records every set of pairs it's given, failing the first failures times.
+type fakeUpdater struct {
+	mu       sync.Mutex
+	sets     [][]string
+	failures int
+}
+
+func (u *fakeUpdater) SetTradingPairs(pairs []string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.sets = append(u.sets, pairs)
+
+	if u.failures > 0 {
+		u.failures--
+		return errors.New("failed to subscribe: websocket closed")
+	}
+
+	return nil
+}
+
+func (u *fakeUpdater) get() [][]string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.sets
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trading_pairs.yaml")
+	writeFile(t, path, pairsYAML)
+
+	t.Run("returns the pairs for the exchange", func(t *testing.T) {
+		pairs, err := pairsfile.Load(path, entities.ExchangeBinance)
+		require.NoError(t, err)
+		require.Equal(t, []string{"BTC/USDT", "ETH/USDT"}, pairs)
+	})
+
+	t.Run("returns every exchange's pairs without duplicates if no exchange is given", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "trading_pairs.yaml")
+		writeFile(t, path, pairsYAML+"    - name: kraken\n      pairs: [XBT/USDT, SOL/USDT]\n")
+
+		pairs, err := pairsfile.Load(path, "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"BTC/USDT", "ETH/USDT", "SOL/USDT"}, pairs)
+	})
+
+	t.Run("fails if the exchange isn't listed", func(t *testing.T) {
+		_, err := pairsfile.Load(path, entities.ExchangeOKX)
+		require.EqualError(t, err, "exchange okx not specified")
+	})
+
+	t.Run("fails if a pair is invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "trading_pairs.yaml")
+		writeFile(t, path, "exchanges:\n    - name: binance\n      pairs: [BTC/USDT, ETHUSDT]\n")
+
+		_, err := pairsfile.Load(path, entities.ExchangeBinance)
+		require.EqualError(t, err, "failed to parse "+path+" for binance: invalid pair ETHUSDT")
+	})
+}
+
+func TestExchanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trading_pairs.yaml")
+	writeFile(t, path, pairsYAML)
+
+	exchanges, err := pairsfile.Exchanges(path)
+	require.NoError(t, err)
+	require.Equal(t, []entities.Exchange{entities.ExchangeBinance, entities.ExchangeKuCoin}, exchanges)
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("replaces the given exchanges and keeps the others", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "trading_pairs.yaml")
+		writeFile(t, path, pairsYAML)
+
+		err := pairsfile.Update(path, map[entities.Exchange][]string{
+			entities.ExchangeKuCoin: {"ETH/USDT", "SOL/USDT"},
+			entities.ExchangeOKX:    {"SOL/USDT"},
+		})
+		require.NoError(t, err)
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, `exchanges:
+    - name: binance
+      pairs:
+        - BTC/USDT
+        - ETH/USDT
+    - name: kucoin
+      pairs:
+        - ETH/USDT
+        - SOL/USDT
+    - name: okx
+      pairs:
+        - SOL/USDT
+`, string(b))
+	})
+
+	t.Run("creates the file if it doesn't exist", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "trading_pairs.yaml")
+
+		err := pairsfile.Update(path, map[entities.Exchange][]string{entities.ExchangeBinance: {"BTC/USDT"}})
+		require.NoError(t, err)
+
+		pairs, err := pairsfile.Load(path, entities.ExchangeBinance)
+		require.NoError(t, err)
+		require.Equal(t, []string{"BTC/USDT"}, pairs)
+	})
+}
+
+func TestWatcher_Run(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trading_pairs.yaml")
+	writeFile(t, path, pairsYAML)
+
+	u := &fakeUpdater{}
+	w := pairsfile.NewWatcher(pairsfile.WatcherConfig{
+		Exchange: entities.ExchangeBinance,
+		Interval: 5 * time.Millisecond,
+		Path:     path,
+		Updater:  u,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	// Unchanged, reordered and unreadable files don't change the pairs
+	time.Sleep(20 * time.Millisecond)
+	writeFile(t, path, "exchanges:\n    - name: binance\n      pairs: [ETH/USDT, BTC/USDT]\n")
+	time.Sleep(20 * time.Millisecond)
+	writeFile(t, path, "exchanges: [")
+	time.Sleep(20 * time.Millisecond)
+	require.Empty(t, u.get())
+
+	writeFile(t, path, "exchanges:\n    - name: binance\n      pairs: [ETH/USDT, SOL/USDT]\n")
+	require.Eventually(t, func() bool { return len(u.get()) == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, []string{"ETH/USDT", "SOL/USDT"}, u.get()[0])
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestWatcher_Run_Retries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trading_pairs.yaml")
+	writeFile(t, path, pairsYAML)
+
+	u := &fakeUpdater{failures: 2}
+	w := pairsfile.NewWatcher(pairsfile.WatcherConfig{
+		Exchange: entities.ExchangeBinance,
+		Interval: 5 * time.Millisecond,
+		Path:     path,
+		Updater:  u,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	writeFile(t, path, "exchanges:\n    - name: binance\n      pairs: [ETH/USDT, SOL/USDT]\n")
+
+	// Given the pairs until they're set, then left alone
+	require.Eventually(t, func() bool { return len(u.get()) == 3 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	require.Len(t, u.get(), 3)
+
+	for _, pairs := range u.get() {
+		require.Equal(t, []string{"ETH/USDT", "SOL/USDT"}, pairs)
+	}
+
+	cancel()
+	require.ErrorIs(t, <-done, context.Canceled)
+}
+
+// textExchange subscribes with plain text messages, e.g. "subscribe BTC-USDT", failing to create the first
+// unsubscribeFailures unsubscribe messages.
+type textExchange struct {
+	url string
+
+	mu                  sync.Mutex
+	unsubscribeFailures int
+}
+
+func (e *textExchange) Name() entities.Exchange { return entities.ExchangeBinance }
+
+func (e *textExchange) Symbol(pair entities.Pair) string {
+	return pair.Base.String() + "-" + pair.Quote.String()
+}
+
+func (e *textExchange) Bootstrap(context.Context) (connector.Connection, error) {
+	return connector.Connection{URL: e.url}, nil
+}
+
+func (e *textExchange) SubscribeMessages(symbols []string) ([][]byte, error) {
+	return [][]byte{[]byte("subscribe " + strings.Join(symbols, ","))}, nil
+}
+
+func (e *textExchange) UnsubscribeMessages(symbols []string) ([][]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.unsubscribeFailures > 0 {
+		e.unsubscribeFailures--
+		return nil, errors.New("rate limited")
+	}
+
+	return [][]byte{[]byte("unsubscribe " + strings.Join(symbols, ","))}, nil
+}
+
+func (e *textExchange) Decode([]byte) (connector.Message, error) { return connector.Message{}, nil }
+
+func (e *textExchange) KeepaliveMessage() ([]byte, error) { return nil, nil }
+
+func TestWatcher_Run_Client(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trading_pairs.yaml")
+	writeFile(t, path, pairsYAML)
+
+	// Sends every message the server receives to the channel
+	received := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+
+		for {
+			_, p, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			received <- string(p)
+		}
+	}))
+	defer srv.Close()
+
+	pairs, err := pairsfile.Load(path, entities.ExchangeBinance)
+	require.NoError(t, err)
+
+	c, err := connector.NewClient(connector.Config{
+		Exchange:     &textExchange{url: "ws" + strings.TrimPrefix(srv.URL, "http"), unsubscribeFailures: 1},
+		TradingPairs: pairs,
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go c.Run(ctx)
+	require.Contains(t, []string{"subscribe BTC-USDT,ETH-USDT", "subscribe ETH-USDT,BTC-USDT"}, <-received)
+	require.Eventually(t, func() bool { return c.CheckConnected(ctx) == nil }, time.Second, time.Millisecond)
+
+	w := pairsfile.NewWatcher(pairsfile.WatcherConfig{
+		Exchange: entities.ExchangeBinance,
+		Interval: 5 * time.Millisecond,
+		Path:     path,
+		Updater:  c,
+	})
+	go w.Run(ctx)
+
+	time.Sleep(20 * time.Millisecond)
+	writeFile(t, path, "exchanges:\n    - name: binance\n      pairs: [ETH/USDT, SOL/USDT]\n")
+
+	// Unsubscribing fails the first time, so is sent on the next tick without subscribing again
+	require.Equal(t, "subscribe SOL-USDT", <-received)
+	require.Equal(t, "unsubscribe BTC-USDT", <-received)
+
+	time.Sleep(20 * time.Millisecond)
+	require.Empty(t, received)
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	arberrors "github.com/peterstirrup/arbenheimer/internal/domain/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain identifies this service in errdetails.ErrorInfo.
+const errorDomain = "arbenheimer"
+
+// domainErrors maps the errors returned by the use cases to a gRPC code and an errdetails.ErrorInfo reason.
+var domainErrors = []struct {
+	err    error
+	code   codes.Code
+	reason string
+}{
+	{arberrors.ErrMarketNotFound, codes.NotFound, "MARKET_NOT_FOUND"},
+	{arberrors.ErrOrderBookNotFound, codes.NotFound, "ORDER_BOOK_NOT_FOUND"},
+	{arberrors.ErrExchangeNotFound, codes.InvalidArgument, "EXCHANGE_NOT_FOUND"},
+	{arberrors.ErrInvalidTimeRange, codes.InvalidArgument, "INVALID_TIME_RANGE"},
+	{arberrors.ErrInvalidCandleInterval, codes.InvalidArgument, "INVALID_CANDLE_INTERVAL"},
+	{arberrors.ErrInvalidMarketTimestamp, codes.FailedPrecondition, "INVALID_MARKET_TIMESTAMP"},
+	{arberrors.ErrStreamingUnavailable, codes.Unimplemented, "STREAMING_UNAVAILABLE"},
+	{arberrors.ErrHistoryUnavailable, codes.Unimplemented, "HISTORY_UNAVAILABLE"},
+	{arberrors.ErrCandlesUnavailable, codes.Unimplemented, "CANDLES_UNAVAILABLE"},
+	{arberrors.ErrInstrumentNotFound, codes.NotFound, "INSTRUMENT_NOT_FOUND"},
+	{arberrors.ErrInstrumentsUnavailable, codes.Unimplemented, "INSTRUMENTS_UNAVAILABLE"},
+}
+
+// toStatusError converts an error from the use cases to a gRPC status error, so clients can tell a bad request or
+// a missing market apart from a failure worth retrying, e.g. Redis being down (Unavailable).
+// The trading pair and exchange, if set, are attached as errdetails.ErrorInfo metadata.
+func toStatusError(err error, tradingPair, exchange string) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		// Already a status, e.g. from a failed stream send
+		return err
+	}
+
+	code, reason := codes.Internal, "INTERNAL"
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		code, reason = codes.Canceled, "CANCELED"
+	case errors.Is(err, context.DeadlineExceeded):
+		code, reason = codes.DeadlineExceeded, "DEADLINE_EXCEEDED"
+	case errors.As(err, &netErr):
+		code, reason = codes.Unavailable, "STORE_UNAVAILABLE"
+	}
+
+	for _, e := range domainErrors {
+		if errors.Is(err, e.err) {
+			code, reason = e.code, e.reason
+			break
+		}
+	}
+
+	metadata := make(map[string]string)
+	if tradingPair != "" {
+		metadata["trading_pair"] = tradingPair
+	}
+	if exchange != "" {
+		metadata["exchange"] = exchange
+	}
+
+	st, detailsErr := status.New(code, err.Error()).WithDetails(&errdetails.ErrorInfo{
+		Reason:   reason,
+		Domain:   errorDomain,
+		Metadata: metadata,
+	})
+	if detailsErr != nil {
+		return status.Error(code, err.Error())
+	}
+
+	return st.Err()
+}
+
+// invalidArgument returns an InvalidArgument status error describing what's wrong with a request field.
+func invalidArgument(field, description string) error {
+	msg := fmt.Sprintf("invalid %s: %s", field, description)
+
+	st, err := status.New(codes.InvalidArgument, msg).WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: description}},
+	})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, msg)
+	}
+
+	return st.Err()
+}
+
+// validateTradingPair returns an InvalidArgument status error if the trading pair isn't a canonical BASE/QUOTE pair,
+// as markets are only stored under their canonical pair, e.g. BTC/USD rather than XBT/USD.
+func validateTradingPair(field, tradingPair string) error {
+	pair, err := entities.ParsePair(tradingPair)
+	if err != nil {
+		return invalidArgument(field, fmt.Sprintf("%q must be an upper case BASE/QUOTE trading pair, e.g. BTC/USDT", tradingPair))
+	}
+
+	if pair.String() != tradingPair {
+		return invalidArgument(field, fmt.Sprintf("%q must use canonical asset codes, e.g. %s", tradingPair, pair))
+	}
+
+	return nil
+}
+
+// validateTradingPairs validates each of the trading pairs in a repeated request field.
+func validateTradingPairs(field string, tradingPairs []string) error {
+	for i, tradingPair := range tradingPairs {
+		if err := validateTradingPair(fmt.Sprintf("%s[%d]", field, i), tradingPair); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
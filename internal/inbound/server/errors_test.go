@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	arberrors "github.com/peterstirrup/arbenheimer/internal/domain/errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestToStatusError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{
+			name:   "domain error",
+			err:    fmt.Errorf("failed to get market: %w", arberrors.ErrMarketNotFound),
+			code:   codes.NotFound,
+			reason: "MARKET_NOT_FOUND",
+		},
+		{
+			name:   "invalid argument",
+			err:    arberrors.ErrExchangeNotFound,
+			code:   codes.InvalidArgument,
+			reason: "EXCHANGE_NOT_FOUND",
+		},
+		{
+			name:   "context cancelled",
+			err:    context.Canceled,
+			code:   codes.Canceled,
+			reason: "CANCELED",
+		},
+		{
+			name:   "network error, e.g. Redis down",
+			err:    fmt.Errorf("failed to get market: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}),
+			code:   codes.Unavailable,
+			reason: "STORE_UNAVAILABLE",
+		},
+		{
+			name:   "unknown error",
+			err:    errors.New("failed to unmarshal market"),
+			code:   codes.Internal,
+			reason: "INTERNAL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, ok := status.FromError(toStatusError(tt.err, "BTC/USDT", "binance"))
+			require.True(t, ok)
+			require.Equal(t, tt.code, st.Code())
+			require.Equal(t, tt.err.Error(), st.Message())
+
+			require.Len(t, st.Details(), 1)
+			info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+			require.True(t, ok)
+			require.Equal(t, tt.reason, info.Reason)
+			require.Equal(t, map[string]string{"trading_pair": "BTC/USDT", "exchange": "binance"}, info.Metadata)
+		})
+	}
+
+	t.Run("keeps a status error as it is", func(t *testing.T) {
+		err := status.Error(codes.Unavailable, "transport is closing")
+		require.Equal(t, err, toStatusError(err, "BTC/USDT", ""))
+	})
+}
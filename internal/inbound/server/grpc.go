@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/inbound/server/pb"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	maxConnectionIdle     = 10 * time.Minute
+	maxConnectionAge      = 5 * time.Minute
+	maxConnectionAgeGrace = 5 * time.Minute
+	defaultTime           = 5 * time.Minute
+
+	// stopTimeout is how long RPCs in progress have to finish when shutting down, before they're cancelled.
+	stopTimeout = 10 * time.Second
+)
+
+type GRPCConfig struct {
+	Host          string
+	Port          int
+	Server        *Server
+	ShutdownDelay time.Duration // How long health checks report NOT_SERVING before the server stops
+}
+
+// GRPCServer serves a Server and the gRPC health service over TCP, with tracing and metrics for every RPC.
+// The Run method provides context cancellation handling not provided by the base grpc.Server type.
+type GRPCServer struct {
+	s       *grpc.Server
+	lis     net.Listener
+	hs      *health.Server
+	address string
+
+	shutdownDelay time.Duration
+}
+
+// NewGRPCServer starts listening on the host and port, and returns a gRPC server ready to Run.
+func NewGRPCServer(cfg GRPCConfig) (*GRPCServer, error) {
+	address := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("%w while starting tcp listener", err)
+	}
+	log.Debug().Msg("tcp listener started")
+
+	s := grpc.NewServer(
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     maxConnectionIdle,
+			MaxConnectionAge:      maxConnectionAge,
+			MaxConnectionAgeGrace: maxConnectionAgeGrace,
+			Time:                  defaultTime,
+		}),
+		grpc.ChainUnaryInterceptor(UnaryTracingInterceptor, UnaryMetricsInterceptor),
+		grpc.ChainStreamInterceptor(StreamTracingInterceptor, StreamMetricsInterceptor),
+	)
+	pb.RegisterArbenheimerServiceServer(s, cfg.Server)
+
+	healthServer := health.NewServer()
+	grpc_health_v1.RegisterHealthServer(s, healthServer)
+
+	return &GRPCServer{s: s, lis: lis, address: address, hs: healthServer, shutdownDelay: cfg.ShutdownDelay}, nil
+}
+
+// Health returns the gRPC health service, so its status can follow the process's health checks.
+func (s *GRPCServer) Health() *health.Server {
+	return s.hs
+}
+
+// Run starts the gRPC server and blocks until the context is cancelled.
+// Health checks report NOT_SERVING for the shutdown delay before the server stops, so load balancers stop sending it
+// requests while it still serves them.
+func (s *GRPCServer) Run(ctx context.Context) error {
+	log.Info().Str("address", s.address).Msg("starting gRPC server")
+
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.hs.Shutdown()
+			time.Sleep(s.shutdownDelay)
+			s.gracefulStop()
+			<-done
+
+		case <-done:
+		}
+	}()
+
+	err := s.s.Serve(s.lis)
+	done <- struct{}{}
+
+	if err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// gracefulStop waits up to stopTimeout for RPCs in progress to finish, then stops the server.
+// Streams only end when the client cancels them, so would otherwise block shutting down.
+func (s *GRPCServer) gracefulStop() {
+	stopped := make(chan struct{})
+	go func() {
+		s.s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(stopTimeout):
+		log.Warn().Msg("RPCs still in progress, stopping gRPC server")
+		s.s.Stop()
+		<-stopped
+	}
+}
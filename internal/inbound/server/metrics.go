@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	rpcRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "arbenheimer",
+		Subsystem: "grpc",
+		Name:      "requests_total",
+		Help:      "RPCs handled, by method and status code.",
+	}, []string{"method", "code"})
+
+	rpcDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "arbenheimer",
+		Subsystem: "grpc",
+		Name:      "request_duration_seconds",
+		Help:      "Time taken to handle RPCs, by method. Streams are timed until they end.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+)
+
+// UnaryMetricsInterceptor records the count and latency of unary RPCs.
+func UnaryMetricsInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+
+	resp, err := handler(ctx, req)
+	observeRPC(info.FullMethod, start, err)
+
+	return resp, err
+}
+
+// StreamMetricsInterceptor records the count and duration of streaming RPCs.
+func StreamMetricsInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+
+	err := handler(srv, ss)
+	observeRPC(info.FullMethod, start, err)
+
+	return err
+}
+
+func observeRPC(method string, start time.Time, err error) {
+	rpcRequests.WithLabelValues(method, status.Code(err).String()).Inc()
+	rpcDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+}
@@ -2,58 +2,400 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	arberrors "github.com/peterstirrup/arbenheimer/internal/domain/errors"
 	"github.com/peterstirrup/arbenheimer/internal/inbound/server/pb"
 	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type MarketUseCases interface {
-	GetMarkets(ctx context.Context, tradingPair string) ([]entities.Market, error)
+	GetCandles(ctx context.Context, tradingPair string, exchange entities.Exchange, interval entities.CandleInterval, from, to time.Time) ([]entities.Candle, error)
+	GetExecutableArbitrage(ctx context.Context, tradingPair string, maxNotional decimal.Decimal) ([]entities.Arbitrage, error)
+	GetMarketHistory(ctx context.Context, tradingPair string, exchange entities.Exchange, from, to time.Time, limit int) ([]entities.Market, error)
+	GetMarkets(ctx context.Context, tradingPair string, excludeStale bool) ([]entities.Quote, error)
+	GetMarketsForPairs(ctx context.Context, tradingPairs []string, excludeStale bool) ([]entities.Quote, error)
+	GetOrderBooks(ctx context.Context, tradingPair string, exchanges []entities.Exchange) ([]entities.OrderBook, error)
+	GetTopSpreads(ctx context.Context, tradingPairs []string, limit int) ([]entities.Spread, error)
+	StreamMarkets(ctx context.Context, tradingPairs []string, exchanges []entities.Exchange) (<-chan entities.Market, error)
+}
+
+type InstrumentUseCases interface {
+	GetInstruments(ctx context.Context, tradingPair string, exchanges []entities.Exchange) ([]entities.Instrument, error)
 }
 
 type Server struct {
 	pb.UnimplementedArbenheimerServiceServer
-	market MarketUseCases
+	instruments InstrumentUseCases
+	market      MarketUseCases
 }
 
 type Config struct {
-	MarketUseCases MarketUseCases
+	InstrumentUseCases InstrumentUseCases // Optional, GetInstruments is unavailable without it
+	MarketUseCases     MarketUseCases
 }
 
 func NewServer(cfg Config) *Server {
 	return &Server{
-		market: cfg.MarketUseCases,
+		instruments: cfg.InstrumentUseCases,
+		market:      cfg.MarketUseCases,
 	}
 }
 
-// GetMarket retrieves market data for the given trading pair.
+// GetMarket retrieves market data for the given trading pair, with how stale each quote is.
 // If the trading pair is not found on any exchange, an error is returned.
 func (s *Server) GetMarket(ctx context.Context, req *pb.GetMarketRequest) (*pb.GetMarketResponse, error) {
 	log.Info().Msg("received GetMarket request")
 
-	markets, err := s.market.GetMarkets(ctx, req.TradingPair)
-	if err != nil {
+	if err := validateTradingPair("trading_pair", req.TradingPair); err != nil {
 		return nil, err
 	}
 
+	quotes, err := s.market.GetMarkets(ctx, req.TradingPair, req.ExcludeStale)
+	if err != nil {
+		return nil, toStatusError(err, req.TradingPair, "")
+	}
+
 	resp := &pb.GetMarketResponse{
+		Markets: make([]*pb.Market, 0, len(quotes)),
+	}
+
+	for _, q := range quotes {
+		resp.Markets = append(resp.Markets, toPBQuote(q))
+	}
+
+	return resp, nil
+}
+
+// GetMarkets retrieves market data for many trading pairs in one request.
+// If no trading pairs are given, all configured trading pairs are returned. Pairs that aren't found are skipped.
+func (s *Server) GetMarkets(ctx context.Context, req *pb.GetMarketsRequest) (*pb.GetMarketsResponse, error) {
+	log.Info().Msg("received GetMarkets request")
+
+	if err := validateTradingPairs("trading_pairs", req.TradingPairs); err != nil {
+		return nil, err
+	}
+
+	quotes, err := s.market.GetMarketsForPairs(ctx, req.TradingPairs, req.ExcludeStale)
+	if err != nil {
+		return nil, toStatusError(err, "", "")
+	}
+
+	resp := &pb.GetMarketsResponse{
+		Markets: make([]*pb.Market, 0, len(quotes)),
+	}
+
+	for _, q := range quotes {
+		resp.Markets = append(resp.Markets, toPBQuote(q))
+	}
+
+	return resp, nil
+}
+
+// GetTopSpreads retrieves the trading pairs with the largest spread between exchanges.
+// If no trading pairs are given, all configured trading pairs are checked.
+func (s *Server) GetTopSpreads(ctx context.Context, req *pb.GetTopSpreadsRequest) (*pb.GetTopSpreadsResponse, error) {
+	log.Info().Msg("received GetTopSpreads request")
+
+	if err := validateTradingPairs("trading_pairs", req.TradingPairs); err != nil {
+		return nil, err
+	}
+
+	spreads, err := s.market.GetTopSpreads(ctx, req.TradingPairs, int(req.Limit))
+	if err != nil {
+		return nil, toStatusError(err, "", "")
+	}
+
+	resp := &pb.GetTopSpreadsResponse{
+		Spreads: make([]*pb.Spread, 0, len(spreads)),
+	}
+
+	for _, sp := range spreads {
+		resp.Spreads = append(resp.Spreads, &pb.Spread{
+			TradingPair:      sp.TradingPair,
+			BuyExchange:      sp.BuyExchange.String(),
+			SellExchange:     sp.SellExchange.String(),
+			BuyPrice:         sp.BuyPrice.String(),
+			SellPrice:        sp.SellPrice.String(),
+			Spread:           sp.Spread.String(),
+			SpreadPercent:    sp.SpreadPercent.String(),
+			BuyQuoteAge:      durationpb.New(sp.BuyQuoteAge),
+			SellQuoteAge:     durationpb.New(sp.SellQuoteAge),
+			BuyFee:           sp.BuyFee.String(),
+			SellFee:          sp.SellFee.String(),
+			NetSpread:        sp.NetSpread.String(),
+			NetSpreadPercent: sp.NetSpreadPercent.String(),
+		})
+	}
+
+	return resp, nil
+}
+
+// GetExecutableArbitrage sizes the largest profitable trade for the given trading pair between each pair of exchanges,
+// using their order books. If max_notional is set, no more than that is spent buying.
+func (s *Server) GetExecutableArbitrage(ctx context.Context, req *pb.GetExecutableArbitrageRequest) (*pb.GetExecutableArbitrageResponse, error) {
+	log.Info().Msg("received GetExecutableArbitrage request")
+
+	if err := validateTradingPair("trading_pair", req.TradingPair); err != nil {
+		return nil, err
+	}
+
+	maxNotional := decimal.Zero
+	if req.MaxNotional != "" {
+		var err error
+		maxNotional, err = decimal.NewFromString(req.MaxNotional)
+		if err != nil || maxNotional.IsNegative() {
+			return nil, invalidArgument("max_notional", fmt.Sprintf("%q must be a non-negative decimal", req.MaxNotional))
+		}
+	}
+
+	arbitrages, err := s.market.GetExecutableArbitrage(ctx, req.TradingPair, maxNotional)
+	if err != nil {
+		return nil, toStatusError(err, req.TradingPair, "")
+	}
+
+	resp := &pb.GetExecutableArbitrageResponse{
+		Arbitrages: make([]*pb.Arbitrage, 0, len(arbitrages)),
+	}
+
+	for _, a := range arbitrages {
+		resp.Arbitrages = append(resp.Arbitrages, &pb.Arbitrage{
+			TradingPair:  a.TradingPair,
+			BuyExchange:  a.BuyExchange.String(),
+			SellExchange: a.SellExchange.String(),
+			Quantity:     a.Quantity.String(),
+			BuyVwap:      a.BuyVWAP.String(),
+			SellVwap:     a.SellVWAP.String(),
+			BuyNotional:  a.BuyNotional.String(),
+			SellNotional: a.SellNotional.String(),
+			Profit:       a.Profit.String(),
+		})
+	}
+
+	return resp, nil
+}
+
+// GetCandles retrieves the candles for the given trading pair, exchange and interval opened between from and to,
+// oldest first. If to isn't set, it defaults to now.
+func (s *Server) GetCandles(ctx context.Context, req *pb.GetCandlesRequest) (*pb.GetCandlesResponse, error) {
+	log.Info().Msg("received GetCandles request")
+
+	if err := validateTradingPair("trading_pair", req.TradingPair); err != nil {
+		return nil, err
+	}
+
+	if req.From == nil {
+		return nil, invalidArgument("from", "is required")
+	}
+
+	to := time.Now()
+	if req.To != nil {
+		to = req.To.AsTime()
+	}
+
+	candles, err := s.market.GetCandles(ctx, req.TradingPair, entities.Exchange(req.Exchange), entities.CandleInterval(req.Interval), req.From.AsTime(), to)
+	if err != nil {
+		return nil, toStatusError(err, req.TradingPair, req.Exchange)
+	}
+
+	resp := &pb.GetCandlesResponse{
+		Candles: make([]*pb.Candle, 0, len(candles)),
+	}
+
+	for _, c := range candles {
+		resp.Candles = append(resp.Candles, &pb.Candle{
+			TradingPair: c.TradingPair,
+			Exchange:    c.Exchange.String(),
+			Interval:    c.Interval.String(),
+			OpenTime:    timestamppb.New(c.OpenTime),
+			Open:        c.Open.String(),
+			High:        c.High.String(),
+			Low:         c.Low.String(),
+			Close:       c.Close.String(),
+			Volume:      strconv.FormatFloat(c.Volume, 'f', -1, 64),
+		})
+	}
+
+	return resp, nil
+}
+
+// GetMarketHistory retrieves the market data for the given trading pair on the given exchange between from and to,
+// oldest first, up to the limit. If to isn't set, it defaults to now.
+func (s *Server) GetMarketHistory(ctx context.Context, req *pb.GetMarketHistoryRequest) (*pb.GetMarketHistoryResponse, error) {
+	log.Info().Msg("received GetMarketHistory request")
+
+	if err := validateTradingPair("trading_pair", req.TradingPair); err != nil {
+		return nil, err
+	}
+
+	if req.From == nil {
+		return nil, invalidArgument("from", "is required")
+	}
+
+	to := time.Now()
+	if req.To != nil {
+		to = req.To.AsTime()
+	}
+
+	markets, err := s.market.GetMarketHistory(ctx, req.TradingPair, entities.Exchange(req.Exchange), req.From.AsTime(), to, int(req.Limit))
+	if err != nil {
+		return nil, toStatusError(err, req.TradingPair, req.Exchange)
+	}
+
+	resp := &pb.GetMarketHistoryResponse{
 		Markets: make([]*pb.Market, 0, len(markets)),
 	}
 
 	for _, m := range markets {
-		resp.Markets = append(resp.Markets, &pb.Market{
-			TradingPair:     m.TradingPair,
-			Exchange:        m.Exchange.String(),
-			Timestamp:       timestamppb.New(m.Timestamp),
-			LastTradedPrice: m.LastTradedPrice.String(),
-			BestBuyPrice:    m.BestBuyPrice.String(),
-			BestSellPrice:   m.BestSellPrice.String(),
-			Volume_24Hr:     strconv.FormatFloat(m.Volume24hr, 'f', -1, 64),
+		resp.Markets = append(resp.Markets, toPBMarket(m))
+	}
+
+	return resp, nil
+}
+
+// GetOrderBook retrieves the order book for the given trading pair on the given exchange, or on all exchanges
+// if none is given. If the order book is not found on any exchange, an error is returned.
+func (s *Server) GetOrderBook(ctx context.Context, req *pb.GetOrderBookRequest) (*pb.GetOrderBookResponse, error) {
+	log.Info().Msg("received GetOrderBook request")
+
+	if err := validateTradingPair("trading_pair", req.TradingPair); err != nil {
+		return nil, err
+	}
+
+	var exchanges []entities.Exchange
+	if req.Exchange != "" {
+		exchanges = []entities.Exchange{entities.Exchange(req.Exchange)}
+	}
+
+	orderBooks, err := s.market.GetOrderBooks(ctx, req.TradingPair, exchanges)
+	if err != nil {
+		return nil, toStatusError(err, req.TradingPair, req.Exchange)
+	}
+
+	resp := &pb.GetOrderBookResponse{
+		OrderBooks: make([]*pb.OrderBook, 0, len(orderBooks)),
+	}
+
+	for _, ob := range orderBooks {
+		resp.OrderBooks = append(resp.OrderBooks, &pb.OrderBook{
+			TradingPair: ob.TradingPair,
+			Exchange:    ob.Exchange.String(),
+			Timestamp:   timestamppb.New(ob.Timestamp),
+			Sequence:    ob.Sequence,
+			Bids:        toPBOrderBookLevels(ob.Bids),
+			Asks:        toPBOrderBookLevels(ob.Asks),
 		})
 	}
 
 	return resp, nil
 }
+
+// GetInstruments retrieves the order constraints for the given trading pair on the given exchange, or on all exchanges
+// if none is given. If the trading pair is not found on any exchange, an error is returned.
+func (s *Server) GetInstruments(ctx context.Context, req *pb.GetInstrumentsRequest) (*pb.GetInstrumentsResponse, error) {
+	log.Info().Msg("received GetInstruments request")
+
+	if err := validateTradingPair("trading_pair", req.TradingPair); err != nil {
+		return nil, err
+	}
+
+	if s.instruments == nil {
+		return nil, toStatusError(arberrors.ErrInstrumentsUnavailable, req.TradingPair, req.Exchange)
+	}
+
+	var exchanges []entities.Exchange
+	if req.Exchange != "" {
+		exchanges = []entities.Exchange{entities.Exchange(req.Exchange)}
+	}
+
+	instruments, err := s.instruments.GetInstruments(ctx, req.TradingPair, exchanges)
+	if err != nil {
+		return nil, toStatusError(err, req.TradingPair, req.Exchange)
+	}
+
+	resp := &pb.GetInstrumentsResponse{
+		Instruments: make([]*pb.Instrument, 0, len(instruments)),
+	}
+
+	for _, i := range instruments {
+		resp.Instruments = append(resp.Instruments, &pb.Instrument{
+			TradingPair: i.TradingPair,
+			Exchange:    i.Exchange.String(),
+			Status:      string(i.Status),
+			TickSize:    i.TickSize.String(),
+			StepSize:    i.StepSize.String(),
+			MinQuantity: i.MinQuantity.String(),
+			MaxQuantity: i.MaxQuantity.String(),
+			MinNotional: i.MinNotional.String(),
+		})
+	}
+
+	return resp, nil
+}
+
+// StreamMarkets sends market data for the given trading pairs and exchanges as it is updated.
+// Blocks until the client disconnects.
+func (s *Server) StreamMarkets(req *pb.StreamMarketsRequest, stream pb.ArbenheimerService_StreamMarketsServer) error {
+	log.Info().Msg("received StreamMarkets request")
+
+	if err := validateTradingPairs("trading_pairs", req.TradingPairs); err != nil {
+		return err
+	}
+
+	exchanges := make([]entities.Exchange, 0, len(req.Exchanges))
+	for _, e := range req.Exchanges {
+		exchanges = append(exchanges, entities.Exchange(e))
+	}
+
+	markets, err := s.market.StreamMarkets(stream.Context(), req.TradingPairs, exchanges)
+	if err != nil {
+		return toStatusError(err, "", "")
+	}
+
+	for m := range markets {
+		if err = stream.Send(toPBMarket(m)); err != nil {
+			return toStatusError(err, m.TradingPair, m.Exchange.String())
+		}
+	}
+
+	return toStatusError(stream.Context().Err(), "", "")
+}
+
+func toPBMarket(m entities.Market) *pb.Market {
+	return &pb.Market{
+		TradingPair:     m.TradingPair,
+		Exchange:        m.Exchange.String(),
+		Timestamp:       timestamppb.New(m.Timestamp),
+		LastTradedPrice: m.LastTradedPrice.String(),
+		BestBuyPrice:    m.BestBuyPrice.String(),
+		BestSellPrice:   m.BestSellPrice.String(),
+		Volume_24Hr:     strconv.FormatFloat(m.Volume24hr, 'f', -1, 64),
+	}
+}
+
+// toPBQuote converts a quote to a market, with its age and whether it's stale.
+func toPBQuote(q entities.Quote) *pb.Market {
+	m := toPBMarket(q.Market)
+	m.QuoteAge = durationpb.New(q.Age)
+	m.Stale = q.Stale
+
+	return m
+}
+
+func toPBOrderBookLevels(levels []entities.OrderBookLevel) []*pb.OrderBookLevel {
+	pbLevels := make([]*pb.OrderBookLevel, 0, len(levels))
+	for _, l := range levels {
+		pbLevels = append(pbLevels, &pb.OrderBookLevel{
+			Price:    l.Price.String(),
+			Quantity: l.Quantity.String(),
+		})
+	}
+
+	return pbLevels
+}
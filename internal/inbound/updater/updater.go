@@ -0,0 +1,155 @@
+// Package updater runs one exchange's websocket feed as its own process, storing its markets in Redis. It's shared
+// by the per-exchange updater binaries, which only build their exchange.
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/usecases"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/connector"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/monitoring"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/pairsfile"
+	"github.com/peterstirrup/arbenheimer/internal/outbound/redis"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// tradingPairsFile lists the trading pairs of each exchange. It's watched, so pairs can be changed while running.
+const tradingPairsFile = "data/trading_pairs.yaml"
+
+// FeedArgs are the command line arguments of an exchange's websocket client, shared by the updaters and arbenheimer.
+type FeedArgs struct {
+	DataTimeout           time.Duration `arg:"--data-timeout,env:DATA_TIMEOUT" default:"1m" help:"exchange connections with no tickers, order book updates or heartbeats for this long are reconnected"`
+	DeadAfter             time.Duration `arg:"--dead-after,env:DEAD_AFTER" default:"5m" help:"feeds with no market data for this long fail /healthz, so are restarted"`
+	ReadTimeout           time.Duration `arg:"--read-timeout,env:READ_TIMEOUT" default:"30s" help:"exchange connections with no frames for this long are reconnected"`
+	ReconnectInitialDelay time.Duration `arg:"--reconnect-initial-delay,env:RECONNECT_INITIAL_DELAY" default:"1s"`
+	ReconnectMaxDelay     time.Duration `arg:"--reconnect-max-delay,env:RECONNECT_MAX_DELAY" default:"1m"`
+	ReconnectResetAfter   time.Duration `arg:"--reconnect-reset-after,env:RECONNECT_RESET_AFTER" default:"1m" help:"connections that stay up this long reset the reconnect delay"`
+}
+
+// NewClient returns the websocket client that keeps the markets of the exchange's pairs up to date.
+func (a FeedArgs) NewClient(e connector.Exchange, pairs []string, u connector.MarketUpdaterUseCases) (*connector.Client, error) {
+	return connector.NewClient(connector.Config{
+		Exchange: e,
+		Reconnect: connector.Backoff{
+			InitialDelay: a.ReconnectInitialDelay,
+			MaxDelay:     a.ReconnectMaxDelay,
+			ResetAfter:   a.ReconnectResetAfter,
+		},
+		TradingPairs: pairs,
+		UseCases:     u,
+		Watchdog: connector.Watchdog{
+			DeadAfter:   a.DeadAfter,
+			ReadTimeout: a.ReadTimeout,
+			DataTimeout: a.DataTimeout,
+		},
+	})
+}
+
+// Args are the command line arguments of every updater, embedded in each updater's own arguments.
+type Args struct {
+	FeedArgs
+	LogLevel           string  `arg:"--log-level,env:LOG_LEVEL" default:"debug"`
+	MetricsPort        int     `arg:"--metrics-port,env:METRICS_PORT" default:"9090"`
+	RedisHost          string  `arg:"--redis-host,required,env:REDIS_HOST"`
+	RedisPort          string  `arg:"--redis-port,required,env:REDIS_PORT"`
+	Tracing            string  `arg:"--tracing,env:TRACING" default:"none" help:"none, stdout or otlp"`
+	TracingSampleRatio float64 `arg:"--tracing-sample-ratio,env:TRACING_SAMPLE_RATIO" default:"1" help:"fraction of traces recorded"`
+}
+
+// Run connects to the exchange and updates the markets of its pairs in trading_pairs.yaml until SIGINT or SIGTERM,
+// serving metrics and health checks and watching the file for changed pairs. Returns an error if it can't be started
+// or the connection fails for good.
+func Run(args Args, e connector.Exchange) error {
+	// Cancelled on SIGINT or SIGTERM, e.g. when a deploy stops the container
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logLevel, err := zerolog.ParseLevel(args.LogLevel)
+	if err != nil {
+		log.Warn().Msg("Failed to parse log level, defaulting to debug")
+		logLevel = zerolog.DebugLevel
+	}
+	zerolog.SetGlobalLevel(logLevel)
+
+	shutdownTracing, err := monitoring.SetupTracing(ctx, monitoring.TracingConfig{
+		Exporter:    args.Tracing,
+		SampleRatio: args.TracingSampleRatio,
+		ServiceName: e.Name().String() + "updater",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error().Err(err).Msg("Failed to shut down tracing")
+		}
+	}()
+
+	pairs, err := pairsfile.Load(tradingPairsFile, e.Name())
+	if err != nil {
+		return fmt.Errorf("failed to get trading pairs for exchange: %w", err)
+	}
+
+	rc := redis.NewClient(redis.Config{Host: args.RedisHost, Port: args.RedisPort})
+	// The client returns once the last update has been written, so nothing is lost closing Redis
+	defer func() {
+		if err := rc.Close(); err != nil {
+			log.Err(err).Msg("Failed to close Redis client")
+		}
+	}()
+
+	u := usecases.NewMarket(usecases.MarketConfig{
+		Broker:  rc,
+		Candles: rc,
+		History: rc,
+		Store:   rc,
+		TimeNow: time.Now,
+	})
+
+	ws, err := args.NewClient(e, pairs, u)
+	if err != nil {
+		return fmt.Errorf("failed to create websocket client: %w", err)
+	}
+
+	ms := monitoring.NewServer(monitoring.Config{
+		Health: monitoring.NewHealth(monitoring.HealthConfig{
+			Checks: []monitoring.HealthCheck{
+				{Name: "redis", Kind: monitoring.Readiness, Check: rc.Ping},
+				{Name: "websocket", Kind: monitoring.Readiness, Check: ws.CheckConnected},
+				{Name: "feed", Kind: monitoring.Liveness, Check: ws.CheckAlive},
+			},
+		}),
+		Port: args.MetricsPort,
+	})
+	go func() {
+		if err := ms.Run(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to run monitoring server")
+		}
+	}()
+
+	pw := pairsfile.NewWatcher(pairsfile.WatcherConfig{
+		Exchange: e.Name(),
+		Path:     tradingPairsFile,
+		Updater:  ws,
+	})
+	go func() {
+		if err := pw.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			log.Error().Err(err).Msg("Failed to watch trading pairs")
+		}
+	}()
+
+	if err := ws.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("failed to run websocket client: %w", err)
+	}
+
+	log.Info().Msg("Shut down")
+
+	return nil
+}
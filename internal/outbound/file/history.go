@@ -0,0 +1,243 @@
+// Package file keeps market history in append-only JSON lines files, one per exchange, trading pair and day.
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+)
+
+// maxLineSize is the longest line read from a history file.
+const maxLineSize = 64 * 1024
+
+// dayLayout names the history file for each day, e.g. 2024-10-22.jsonl.
+const dayLayout = "2006-01-02"
+
+type History struct {
+	dir       string
+	retention time.Duration
+
+	mu    sync.Mutex
+	files map[string]*os.File // Path --> file opened for appending
+}
+
+type HistoryConfig struct {
+	Dir       string        // e.g. "history", created if it doesn't exist
+	Retention time.Duration // How long history is kept, defaults to 7 days
+}
+
+func NewHistory(cfg HistoryConfig) (*History, error) {
+	if cfg.Retention == 0 {
+		// Default, matching the Redis history
+		cfg.Retention = 7 * 24 * time.Hour
+	}
+
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create history dir: %w", err)
+	}
+
+	return &History{
+		dir:       cfg.Dir,
+		files:     make(map[string]*os.File),
+		retention: cfg.Retention,
+	}, nil
+}
+
+// AppendMarket appends market data to the market's history file for the day of its timestamp. When a new day's file
+// is started, the files of days older than the retention are deleted.
+func (h *History) AppendMarket(_ context.Context, market entities.Market) error {
+	dir, err := h.marketDir(market.Exchange, market.TradingPair)
+	if err != nil {
+		return err
+	}
+
+	marketData, err := json.Marshal(market)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, market.Timestamp.UTC().Format(dayLayout)+".jsonl")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	f, ok := h.files[path]
+	if !ok {
+		if f, err = h.startDay(dir, path, market.Timestamp); err != nil {
+			return err
+		}
+	}
+
+	_, err = f.Write(append(marketData, '\n'))
+	return err
+}
+
+// startDay opens a day's history file for appending, closing the market's other open files and deleting the days
+// older than the retention.
+func (h *History) startDay(dir, path string, timestamp time.Time) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	for p, open := range h.files {
+		if filepath.Dir(p) == dir {
+			open.Close()
+			delete(h.files, p)
+		}
+	}
+	h.files[path] = f
+
+	days, err := h.days(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := timestamp.Add(-h.retention).UTC().Format(dayLayout)
+	for _, day := range days {
+		if day < cutoff {
+			if err = os.Remove(filepath.Join(dir, day+".jsonl")); err != nil {
+				return nil, fmt.Errorf("failed to delete old history: %w", err)
+			}
+		}
+	}
+
+	return f, nil
+}
+
+// GetMarketHistory reads the first limit markets timestamped from from to to inclusive, oldest first. Only the files
+// of the days in between are read, stopping once limit markets are found.
+func (h *History) GetMarketHistory(_ context.Context, exchange entities.Exchange, tradingPair string, from, to time.Time, limit int) ([]entities.Market, error) {
+	dir, err := h.marketDir(exchange, tradingPair)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := h.days(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fromDay, toDay := from.UTC().Format(dayLayout), to.UTC().Format(dayLayout)
+
+	var markets []entities.Market
+
+	for _, day := range days {
+		if day < fromDay || day > toDay {
+			continue
+		}
+
+		dayMarkets, err := readDay(filepath.Join(dir, day+".jsonl"), from, to)
+		if err != nil {
+			// Deleted past the retention since the dir was listed, so it's no longer history
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		// Appended in the order they were accepted, which is almost always timestamp order
+		sort.SliceStable(dayMarkets, func(i, j int) bool {
+			return dayMarkets[i].Timestamp.Before(dayMarkets[j].Timestamp)
+		})
+
+		markets = append(markets, dayMarkets...)
+		if len(markets) >= limit {
+			return markets[:limit], nil
+		}
+	}
+
+	return markets, nil
+}
+
+// readDay reads the market data in a day's history file timestamped from from to to inclusive.
+func readDay(path string, from, to time.Time) ([]entities.Market, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var markets []entities.Market
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, maxLineSize), maxLineSize)
+
+	for scanner.Scan() {
+		var market entities.Market
+		if err = json.Unmarshal(scanner.Bytes(), &market); err != nil {
+			// A partly written line, e.g. after a crash
+			continue
+		}
+
+		if market.Timestamp.Before(from) || market.Timestamp.After(to) {
+			continue
+		}
+
+		markets = append(markets, market)
+	}
+
+	return markets, scanner.Err()
+}
+
+// days returns the days with a history file in a market's dir, oldest first, e.g. ["2024-10-21", "2024-10-22"].
+func (h *History) days(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var days []string
+	for _, e := range entries {
+		day, ok := strings.CutSuffix(e.Name(), ".jsonl")
+		if _, err = time.Parse(dayLayout, day); ok && err == nil {
+			days = append(days, day)
+		}
+	}
+
+	// Sorted by name, which is date order
+	return days, nil
+}
+
+// Close closes every open history file.
+func (h *History) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var errs []error
+	for path, f := range h.files {
+		errs = append(errs, f.Close())
+		delete(h.files, path)
+	}
+
+	return errors.Join(errs...)
+}
+
+// marketDir returns the dir of a market's daily history files, e.g. <dir>/binance/BTC-USDT.
+// The trading pair comes from requests, so anything that could escape the history dir is rejected.
+func (h *History) marketDir(exchange entities.Exchange, tradingPair string) (string, error) {
+	name := strings.ReplaceAll(tradingPair, "/", "-")
+	if name == "" || strings.ContainsAny(name, `\.`) || strings.ContainsAny(exchange.String(), `/\.`) {
+		return "", fmt.Errorf("invalid trading pair %s on %s", tradingPair, exchange)
+	}
+
+	return filepath.Join(h.dir, exchange.String(), name), nil
+}
@@ -0,0 +1,61 @@
+package file_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/outbound/file"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	market := func(day int) entities.Market {
+		return entities.Market{
+			Exchange:    entities.ExchangeBinance,
+			TradingPair: "BTC/USDT",
+			Timestamp:   start.AddDate(0, 0, day),
+		}
+	}
+
+	dir := t.TempDir()
+	h, err := file.NewHistory(file.HistoryConfig{Dir: dir, Retention: 48 * time.Hour})
+	require.NoError(t, err)
+	t.Cleanup(func() { h.Close() })
+
+	for day := range 4 {
+		require.NoError(t, h.AppendMarket(ctx, market(day)))
+	}
+
+	t.Run("deletes days older than the retention", func(t *testing.T) {
+		entries, err := os.ReadDir(filepath.Join(dir, "binance", "BTC-USDT"))
+		require.NoError(t, err)
+
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		require.Equal(t, []string{"2024-10-02.jsonl", "2024-10-03.jsonl", "2024-10-04.jsonl"}, names)
+	})
+
+	t.Run("reads the days in range", func(t *testing.T) {
+		markets, err := h.GetMarketHistory(ctx, entities.ExchangeBinance, "BTC/USDT", start.AddDate(0, 0, 2), start.AddDate(0, 0, 5), 10)
+		require.NoError(t, err)
+		require.Len(t, markets, 2)
+		require.True(t, markets[0].Timestamp.Equal(market(2).Timestamp))
+		require.True(t, markets[1].Timestamp.Equal(market(3).Timestamp))
+	})
+
+	t.Run("returns the oldest markets up to the limit", func(t *testing.T) {
+		markets, err := h.GetMarketHistory(ctx, entities.ExchangeBinance, "BTC/USDT", start, start.AddDate(0, 0, 5), 1)
+		require.NoError(t, err)
+		require.Len(t, markets, 1)
+		require.True(t, markets[0].Timestamp.Equal(market(1).Timestamp))
+	})
+}
@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+	"slices"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/rs/zerolog/log"
+)
+
+// subscriberBuffer is the number of markets buffered for a subscriber before updates are dropped.
+const subscriberBuffer = 256
+
+type subscriber struct {
+	exchanges    []entities.Exchange
+	tradingPairs []string
+	markets      chan entities.Market
+}
+
+// matches reports whether the subscriber wants a market. Empty exchanges or trading pairs match all.
+func (s *subscriber) matches(market entities.Market) bool {
+	if len(s.exchanges) > 0 && !slices.Contains(s.exchanges, market.Exchange) {
+		return false
+	}
+
+	return len(s.tradingPairs) == 0 || slices.Contains(s.tradingPairs, market.TradingPair)
+}
+
+// PublishMarket publishes market data to every matching subscriber.
+// Like Redis pub/sub, a subscriber that isn't keeping up misses updates rather than blocking the publisher.
+func (c *Client) PublishMarket(_ context.Context, market entities.Market) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for s := range c.subscribers {
+		if !s.matches(market) {
+			continue
+		}
+
+		select {
+		case s.markets <- market:
+		default:
+			log.Warn().Str("exchange", market.Exchange.String()).Str("tradingPair", market.TradingPair).Msg("Subscriber is full, dropping market")
+		}
+	}
+
+	return nil
+}
+
+// SubscribeMarkets subscribes to market data published for the given exchanges and trading pairs.
+// Empty exchanges or trading pairs match all. The returned channel is closed when the context is cancelled.
+func (c *Client) SubscribeMarkets(ctx context.Context, exchanges []entities.Exchange, tradingPairs []string) (<-chan entities.Market, error) {
+	s := &subscriber{
+		exchanges:    exchanges,
+		tradingPairs: tradingPairs,
+		markets:      make(chan entities.Market, subscriberBuffer),
+	}
+
+	c.mu.Lock()
+	c.subscribers[s] = struct{}{}
+	c.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		c.mu.Lock()
+		delete(c.subscribers, s)
+		c.mu.Unlock()
+
+		// Safe to close, as publishers hold the lock while sending
+		close(s.markets)
+	}()
+
+	return s.markets, nil
+}
@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+)
+
+// UpdateCandles merges candles into the ones in memory with the same open time, and only the latest maxCandles are
+// kept.
+func (c *Client) UpdateCandles(_ context.Context, candles []entities.Candle) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, candle := range candles {
+		k := candlesKey(candle.Exchange, candle.TradingPair, candle.Interval)
+		cs := c.candles[k]
+
+		// Candles are almost always updated or added at the end
+		i := sort.Search(len(cs), func(i int) bool {
+			return !cs[i].OpenTime.Before(candle.OpenTime)
+		})
+
+		if i < len(cs) && cs[i].OpenTime.Equal(candle.OpenTime) {
+			cs[i].Merge(candle)
+		} else {
+			cs = append(cs, entities.Candle{})
+			copy(cs[i+1:], cs[i:])
+			cs[i] = candle
+		}
+
+		if len(cs) > c.maxCandles {
+			cs = cs[len(cs)-c.maxCandles:]
+		}
+
+		c.candles[k] = cs
+	}
+
+	return nil
+}
+
+// GetCandles retrieves the candles opened from from to to inclusive, oldest first.
+func (c *Client) GetCandles(_ context.Context, exchange entities.Exchange, tradingPair string, interval entities.CandleInterval, from, to time.Time) ([]entities.Candle, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var candles []entities.Candle
+	for _, candle := range c.candles[candlesKey(exchange, tradingPair, interval)] {
+		if candle.OpenTime.Before(from) || candle.OpenTime.After(to) {
+			continue
+		}
+
+		candles = append(candles, candle)
+	}
+
+	return candles, nil
+}
+
+func candlesKey(exchange entities.Exchange, tradingPair string, interval entities.CandleInterval) string {
+	return key(exchange, tradingPair) + ":" + interval.String()
+}
@@ -0,0 +1,171 @@
+// Package memory stores markets and order books in process, for running every service in a single process
+// without Redis.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/domain/errors"
+)
+
+type timeNow func() time.Time
+
+type Client struct {
+	marketTTL  time.Duration
+	maxCandles int
+	timeNow    timeNow
+
+	mu          sync.RWMutex
+	candles     map[string][]entities.Candle         // exchange:pair:interval --> candles, oldest first
+	markets     map[string]entry[entities.Market]    // exchange:pair --> market
+	orderBooks  map[string]entry[entities.OrderBook] // exchange:pair --> order book
+	subscribers map[*subscriber]struct{}
+	nextSweep   time.Time // When expired markets and order books are next deleted
+}
+
+type Config struct {
+	MarketTTL  time.Duration
+	MaxCandles int // Number of candles kept for each market and interval
+	TimeNow    timeNow
+}
+
+// entry is a stored value and when it expires, matching Redis key expiry.
+type entry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+func NewClient(cfg Config) *Client {
+	if cfg.MarketTTL == 0 {
+		// Default
+		cfg.MarketTTL = 10 * time.Minute
+	}
+
+	if cfg.MaxCandles == 0 {
+		// Default, e.g. a week of 1m candles
+		cfg.MaxCandles = 10000
+	}
+
+	if cfg.TimeNow == nil {
+		cfg.TimeNow = time.Now
+	}
+
+	return &Client{
+		candles:     make(map[string][]entities.Candle),
+		marketTTL:   cfg.MarketTTL,
+		maxCandles:  cfg.MaxCandles,
+		markets:     make(map[string]entry[entities.Market]),
+		orderBooks:  make(map[string]entry[entities.OrderBook]),
+		subscribers: make(map[*subscriber]struct{}),
+		timeNow:     cfg.TimeNow,
+	}
+}
+
+// GetMarkets retrieves the market data for every combination of the given exchanges and trading pairs from memory.
+// Markets that aren't found are skipped.
+func (c *Client) GetMarkets(_ context.Context, exchanges []entities.Exchange, tradingPairs []string) ([]entities.Market, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.timeNow()
+
+	var markets []entities.Market
+
+	for _, tradingPair := range tradingPairs {
+		for _, exchange := range exchanges {
+			e, ok := c.markets[key(exchange, tradingPair)]
+			if !ok || !now.Before(e.expiresAt) {
+				continue
+			}
+
+			markets = append(markets, e.value)
+		}
+	}
+
+	return markets, nil
+}
+
+// UpdateMarketIfNewer stores market data in memory, unless the stored market data has a later timestamp.
+func (c *Client) UpdateMarketIfNewer(_ context.Context, market entities.Market) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k := key(market.Exchange, market.TradingPair)
+	now := c.timeNow()
+
+	if e, ok := c.markets[k]; ok && now.Before(e.expiresAt) && e.value.Timestamp.After(market.Timestamp) {
+		return fmt.Errorf("%w: current market data is newer than the provided data", arberrors.ErrInvalidMarketTimestamp)
+	}
+
+	c.markets[k] = entry[entities.Market]{
+		value:     market,
+		expiresAt: now.Add(c.marketTTL),
+	}
+	c.sweep(now)
+
+	return nil
+}
+
+// GetOrderBook retrieves an order book from memory.
+func (c *Client) GetOrderBook(_ context.Context, exchange entities.Exchange, tradingPair string) (entities.OrderBook, error) {
+	c.mu.RLock()
+	e, ok := c.orderBooks[key(exchange, tradingPair)]
+	c.mu.RUnlock()
+
+	if !ok || !c.timeNow().Before(e.expiresAt) {
+		return entities.OrderBook{}, fmt.Errorf("%w for %s on %s", arberrors.ErrOrderBookNotFound, tradingPair, exchange)
+	}
+
+	return e.value, nil
+}
+
+// UpdateOrderBookIfNewer stores an order book in memory, unless the stored order book has a later timestamp.
+func (c *Client) UpdateOrderBookIfNewer(_ context.Context, orderBook entities.OrderBook) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k := key(orderBook.Exchange, orderBook.TradingPair)
+	now := c.timeNow()
+
+	if e, ok := c.orderBooks[k]; ok && now.Before(e.expiresAt) && e.value.Timestamp.After(orderBook.Timestamp) {
+		return fmt.Errorf("%w: current order book is newer than the provided order book", arberrors.ErrInvalidMarketTimestamp)
+	}
+
+	// Expires with the market, a book that old can't be traded on
+	c.orderBooks[k] = entry[entities.OrderBook]{
+		value:     orderBook,
+		expiresAt: now.Add(c.marketTTL),
+	}
+	c.sweep(now)
+
+	return nil
+}
+
+// sweep deletes expired markets and order books, so pairs that are no longer updated don't use memory forever, as
+// Redis would expire them. It runs at most once a TTL, so writes don't each walk every key. c.mu must be held.
+func (c *Client) sweep(now time.Time) {
+	if now.Before(c.nextSweep) {
+		return
+	}
+	c.nextSweep = now.Add(c.marketTTL)
+
+	for k, e := range c.markets {
+		if !now.Before(e.expiresAt) {
+			delete(c.markets, k)
+		}
+	}
+
+	for k, e := range c.orderBooks {
+		if !now.Before(e.expiresAt) {
+			delete(c.orderBooks, k)
+		}
+	}
+}
+
+func key(exchange entities.Exchange, tradingPair string) string {
+	return exchange.String() + ":" + tradingPair
+}
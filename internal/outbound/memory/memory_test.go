@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_sweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	c := NewClient(Config{MarketTTL: time.Minute, TimeNow: func() time.Time { return now }})
+
+	err := c.UpdateMarketIfNewer(ctx, entities.Market{Exchange: entities.ExchangeBinance, TradingPair: "BTC/USDT", Timestamp: now})
+	require.NoError(t, err)
+	require.NoError(t, c.UpdateOrderBookIfNewer(ctx, entities.OrderBook{Exchange: entities.ExchangeBinance, TradingPair: "BTC/USDT", Timestamp: now}))
+
+	// BTC/USDT is no longer updated, so has expired by the time ETH/USDT is written
+	now = now.Add(2 * time.Minute)
+
+	err = c.UpdateMarketIfNewer(ctx, entities.Market{Exchange: entities.ExchangeBinance, TradingPair: "ETH/USDT", Timestamp: now})
+	require.NoError(t, err)
+	require.NoError(t, c.UpdateOrderBookIfNewer(ctx, entities.OrderBook{Exchange: entities.ExchangeBinance, TradingPair: "ETH/USDT", Timestamp: now}))
+
+	require.Len(t, c.markets, 1)
+	require.Contains(t, c.markets, "binance:ETH/USDT")
+	require.Len(t, c.orderBooks, 1)
+	require.Contains(t, c.orderBooks, "binance:ETH/USDT")
+}
@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/rs/zerolog/log"
+)
+
+// PublishMarket publishes market data to the market's channel.
+func (c *Client) PublishMarket(ctx context.Context, market entities.Market) error {
+	marketData, err := json.Marshal(market)
+	if err != nil {
+		return err
+	}
+
+	return c.rc.Publish(ctx, marketChannel(market.Exchange.String(), market.TradingPair), marketData).Err()
+}
+
+// SubscribeMarkets subscribes to market data published for the given exchanges and trading pairs.
+// Empty exchanges or trading pairs match all. The returned channel is closed when the context is cancelled.
+func (c *Client) SubscribeMarkets(ctx context.Context, exchanges []entities.Exchange, tradingPairs []string) (<-chan entities.Market, error) {
+	exchangePatterns := []string{"*"}
+	if len(exchanges) > 0 {
+		exchangePatterns = make([]string, 0, len(exchanges))
+		for _, e := range exchanges {
+			exchangePatterns = append(exchangePatterns, e.String())
+		}
+	}
+
+	pairPatterns := []string{"*"}
+	if len(tradingPairs) > 0 {
+		pairPatterns = tradingPairs
+	}
+
+	var patterns []string
+	for _, e := range exchangePatterns {
+		for _, p := range pairPatterns {
+			patterns = append(patterns, marketChannel(e, p))
+		}
+	}
+
+	ps := c.rc.PSubscribe(ctx, patterns...)
+
+	// Wait for confirmation so updates published after we return aren't missed
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, err
+	}
+
+	markets := make(chan entities.Market)
+
+	go func() {
+		defer close(markets)
+		defer ps.Close()
+
+		ch := ps.Channel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				var market entities.Market
+				if err := json.Unmarshal([]byte(msg.Payload), &market); err != nil {
+					log.Err(err).Str("channel", msg.Channel).Msg("Failed to unmarshal published market")
+					continue
+				}
+
+				select {
+				case markets <- market:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return markets, nil
+}
+
+func marketChannel(exchange, tradingPair string) string {
+	return "markets:" + exchange + ":" + tradingPair
+}
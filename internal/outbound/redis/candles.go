@@ -0,0 +1,116 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/redis/go-redis/v9"
+)
+
+// maxMergeAttempts is how many times merging candles is tried when another client changes them at the same time.
+const maxMergeAttempts = 10
+
+// UpdateCandles merges candles into a sorted set per market and interval, scored by their open time, and only the
+// latest maxCandles are kept. Candles are merged with Candle.Merge in a transaction that fails if another client
+// changes them first, then retried, so redundant updaters of a market each add their updates and the result is the
+// same as merging in memory.
+func (c *Client) UpdateCandles(ctx context.Context, candles []entities.Candle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(candles))
+	for _, candle := range candles {
+		keys = append(keys, candlesKey(candle.Exchange, candle.TradingPair, candle.Interval))
+	}
+
+	for range maxMergeAttempts {
+		err := c.rc.Watch(ctx, func(tx *redis.Tx) error {
+			return c.mergeCandles(ctx, tx, keys, candles)
+		}, keys...)
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("failed to merge candles after %d attempts: %w", maxMergeAttempts, redis.TxFailedErr)
+}
+
+// mergeCandles merges each candle into the one stored in keys[i] with the same open time, if there is one, and
+// writes them in a transaction that fails if any of the keys have changed since they were watched.
+func (c *Client) mergeCandles(ctx context.Context, tx *redis.Tx, keys []string, candles []entities.Candle) error {
+	merged := make([]entities.Candle, 0, len(candles))
+
+	for i, candle := range candles {
+		score := strconv.FormatInt(candle.OpenTime.UnixMilli(), 10)
+
+		vs, err := tx.ZRangeByScore(ctx, keys[i], &redis.ZRangeBy{Min: score, Max: score}).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(vs) > 0 {
+			var stored entities.Candle
+			if err = json.Unmarshal([]byte(vs[0]), &stored); err != nil {
+				return fmt.Errorf("failed to unmarshal %s: %w", keys[i], err)
+			}
+
+			stored.Merge(candle)
+			candle = stored
+		}
+
+		merged = append(merged, candle)
+	}
+
+	_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, candle := range merged {
+			candleData, err := json.Marshal(candle)
+			if err != nil {
+				return err
+			}
+
+			score := candle.OpenTime.UnixMilli()
+			scoreString := strconv.FormatInt(score, 10)
+
+			pipe.ZRemRangeByScore(ctx, keys[i], scoreString, scoreString)
+			pipe.ZAdd(ctx, keys[i], redis.Z{Score: float64(score), Member: candleData})
+			pipe.ZRemRangeByRank(ctx, keys[i], 0, int64(-c.maxCandles-1))
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// GetCandles retrieves the candles opened from from to to inclusive, oldest first.
+func (c *Client) GetCandles(ctx context.Context, exchange entities.Exchange, tradingPair string, interval entities.CandleInterval, from, to time.Time) ([]entities.Candle, error) {
+	vs, err := c.rc.ZRangeByScore(ctx, candlesKey(exchange, tradingPair, interval), &redis.ZRangeBy{
+		Min: strconv.FormatInt(from.UnixMilli(), 10),
+		Max: strconv.FormatInt(to.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	candles := make([]entities.Candle, 0, len(vs))
+	for _, v := range vs {
+		var candle entities.Candle
+		if err = json.Unmarshal([]byte(v), &candle); err != nil {
+			return nil, err
+		}
+
+		candles = append(candles, candle)
+	}
+
+	return candles, nil
+}
+
+func candlesKey(exchange entities.Exchange, tradingPair string, interval entities.CandleInterval) string {
+	return "candles:" + string(exchange) + ":" + tradingPair + ":" + interval.String()
+}
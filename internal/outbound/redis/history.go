@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/redis/go-redis/v9"
+)
+
+// AppendMarket adds market data to a sorted set of the market's history, scored by its timestamp.
+// History older than the retention is trimmed as new market data is added.
+func (c *Client) AppendMarket(ctx context.Context, market entities.Market) error {
+	marketData, err := json.Marshal(market)
+	if err != nil {
+		return err
+	}
+
+	redisKey := historyKey(market.Exchange, market.TradingPair)
+	cutoff := market.Timestamp.Add(-c.historyRetention).UnixMilli()
+
+	_, err = c.rc.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, redisKey, redis.Z{Score: float64(market.Timestamp.UnixMilli()), Member: marketData})
+		pipe.ZRemRangeByScore(ctx, redisKey, "-inf", "("+strconv.FormatInt(cutoff, 10))
+		return nil
+	})
+
+	return err
+}
+
+// GetMarketHistory retrieves the first limit markets timestamped from from to to inclusive, oldest first.
+func (c *Client) GetMarketHistory(ctx context.Context, exchange entities.Exchange, tradingPair string, from, to time.Time, limit int) ([]entities.Market, error) {
+	vs, err := c.rc.ZRangeByScore(ctx, historyKey(exchange, tradingPair), &redis.ZRangeBy{
+		Min:   strconv.FormatInt(from.UnixMilli(), 10),
+		Max:   strconv.FormatInt(to.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	markets := make([]entities.Market, 0, len(vs))
+	for _, v := range vs {
+		var market entities.Market
+		if err = json.Unmarshal([]byte(v), &market); err != nil {
+			return nil, err
+		}
+
+		markets = append(markets, market)
+	}
+
+	return markets, nil
+}
+
+func historyKey(exchange entities.Exchange, tradingPair string) string {
+	return "history:" + string(exchange) + ":" + tradingPair
+}
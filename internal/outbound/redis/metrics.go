@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	commandDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "arbenheimer",
+		Subsystem: "redis",
+		Name:      "command_duration_seconds",
+		Help:      "Time taken by Redis commands, by command. Pipelines are recorded as a single pipeline command.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"command"})
+
+	commandErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "arbenheimer",
+		Subsystem: "redis",
+		Name:      "command_errors_total",
+		Help:      "Redis commands that failed, not counting missing keys.",
+	}, []string{"command"})
+)
+
+// metricsHook records the latency and errors of every command sent to Redis.
+type metricsHook struct{}
+
+func (metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+
+		err := next(ctx, cmd)
+		observeCommand(cmd.Name(), start, err)
+
+		return err
+	}
+}
+
+func (metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+
+		err := next(ctx, cmds)
+		observeCommand("pipeline", start, err)
+
+		return err
+	}
+}
+
+func observeCommand(command string, start time.Time, err error) {
+	commandDuration.WithLabelValues(command).Observe(time.Since(start).Seconds())
+
+	// Missing keys and scripts being loaded on first use aren't failures
+	if err != nil && !errors.Is(err, redis.Nil) && !redis.HasErrorPrefix(err, "NOSCRIPT") {
+		commandErrors.WithLabelValues(command).Inc()
+	}
+}
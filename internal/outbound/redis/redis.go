@@ -13,57 +13,129 @@ import (
 )
 
 type Client struct {
-	marketTTL time.Duration
-	rc        *redis.Client
+	historyRetention time.Duration
+	marketTTL        time.Duration
+	maxCandles       int
+	rc               *redis.Client
 }
 
 type Config struct {
-	Host      string
-	MarketTTL time.Duration
-	Port      string
+	HistoryRetention time.Duration
+	Host             string
+	MarketTTL        time.Duration
+	MaxCandles       int // Number of candles kept for each market and interval
+	Port             string
 }
 
 func NewClient(cfg Config) *Client {
 	client := redis.NewClient(&redis.Options{
 		Addr: fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
 	})
+	client.AddHook(metricsHook{})
+	client.AddHook(tracingHook{})
 
 	if cfg.MarketTTL == 0 {
 		// Default
 		cfg.MarketTTL = 10 * time.Minute
 	}
 
+	if cfg.HistoryRetention == 0 {
+		// Default
+		cfg.HistoryRetention = 7 * 24 * time.Hour
+	}
+
+	if cfg.MaxCandles == 0 {
+		// Default, e.g. a week of 1m candles
+		cfg.MaxCandles = 10000
+	}
+
 	return &Client{
-		marketTTL: cfg.MarketTTL,
-		rc:        client,
+		historyRetention: cfg.HistoryRetention,
+		marketTTL:        cfg.MarketTTL,
+		maxCandles:       cfg.MaxCandles,
+		rc:               client,
 	}
 }
 
-// GetMarket retrieves market data from Redis.
-func (c *Client) GetMarket(ctx context.Context, exchange entities.Exchange, tradingPair string) (entities.Market, error) {
-	redisKey := "market:" + string(exchange) + ":" + tradingPair
+// Ping returns an error if Redis can't be reached.
+func (c *Client) Ping(ctx context.Context) error {
+	return c.rc.Ping(ctx).Err()
+}
 
-	v, err := c.rc.Get(ctx, redisKey).Result()
-	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			return entities.Market{}, fmt.Errorf("%w for %s on %s", arberrors.ErrMarketNotFound, tradingPair, exchange)
+// Close closes the connections to Redis. Commands already sent are completed first.
+func (c *Client) Close() error {
+	return c.rc.Close()
+}
+
+// GetMarkets retrieves the market data for every combination of the given exchanges and trading pairs from Redis
+// with a single MGET. Markets that aren't found are skipped.
+func (c *Client) GetMarkets(ctx context.Context, exchanges []entities.Exchange, tradingPairs []string) ([]entities.Market, error) {
+	if len(exchanges) == 0 || len(tradingPairs) == 0 {
+		return nil, nil
+	}
+
+	redisKeys := make([]string, 0, len(exchanges)*len(tradingPairs))
+	for _, tradingPair := range tradingPairs {
+		for _, exchange := range exchanges {
+			redisKeys = append(redisKeys, "market:"+string(exchange)+":"+tradingPair)
 		}
-		return entities.Market{}, err
 	}
 
-	var market entities.Market
-	err = json.Unmarshal([]byte(v), &market)
+	vs, err := c.rc.MGet(ctx, redisKeys...).Result()
 	if err != nil {
-		return entities.Market{}, err
+		return nil, err
+	}
+
+	var markets []entities.Market
+
+	for i, v := range vs {
+		s, ok := v.(string)
+		if !ok {
+			// Missing keys are nil
+			continue
+		}
+
+		var market entities.Market
+		err = json.Unmarshal([]byte(s), &market)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal %s: %w", redisKeys[i], err)
+		}
+
+		markets = append(markets, market)
 	}
 
-	return market, nil
+	return markets, nil
+}
+
+// updateIfNewerScript sets KEYS[1] to ARGV[1] with a TTL of ARGV[3] milliseconds, unless the stored value's
+// TimestampMicros is later than ARGV[2]. Returns 1 if the value was written, 0 if not.
+var updateIfNewerScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if current then
+	local ok, stored = pcall(cjson.decode, current)
+	if ok and stored.TimestampMicros and stored.TimestampMicros > tonumber(ARGV[2]) then
+		return 0
+	end
+end
+
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[3])
+return 1
+`)
+
+// storedMarket is a market as stored in Redis. The timestamp is repeated as a number, so it can be compared in Lua.
+type storedMarket struct {
+	entities.Market
+	TimestampMicros int64
 }
 
-// UpdateMarket stores market data in Redis.
-func (c *Client) UpdateMarket(ctx context.Context, market entities.Market) error {
+// UpdateMarketIfNewer stores market data in Redis, unless the stored market data has a later timestamp.
+// The check and write are done atomically by a Lua script.
+func (c *Client) UpdateMarketIfNewer(ctx context.Context, market entities.Market) error {
 	// Serialize Market struct to JSON
-	marketData, err := json.Marshal(market)
+	marketData, err := json.Marshal(storedMarket{
+		Market:          market,
+		TimestampMicros: market.Timestamp.UnixMicro(),
+	})
 	if err != nil {
 		return err
 	}
@@ -71,10 +143,70 @@ func (c *Client) UpdateMarket(ctx context.Context, market entities.Market) error
 	redisKey := "market:" + string(market.Exchange) + ":" + market.TradingPair
 
 	// Price probably useless after 10 minutes
-	err = c.rc.Set(ctx, redisKey, marketData, c.marketTTL).Err()
+	written, err := updateIfNewerScript.Run(ctx, c.rc, []string{redisKey},
+		marketData, market.Timestamp.UnixMicro(), c.marketTTL.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+
+	if written == 0 {
+		return fmt.Errorf("%w: current market data is newer than the provided data", arberrors.ErrInvalidMarketTimestamp)
+	}
+
+	return nil
+}
+
+// GetOrderBook retrieves an order book from Redis.
+func (c *Client) GetOrderBook(ctx context.Context, exchange entities.Exchange, tradingPair string) (entities.OrderBook, error) {
+	redisKey := "orderbook:" + string(exchange) + ":" + tradingPair
+
+	v, err := c.rc.Get(ctx, redisKey).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return entities.OrderBook{}, fmt.Errorf("%w for %s on %s", arberrors.ErrOrderBookNotFound, tradingPair, exchange)
+		}
+		return entities.OrderBook{}, err
+	}
+
+	var orderBook entities.OrderBook
+	err = json.Unmarshal([]byte(v), &orderBook)
+	if err != nil {
+		return entities.OrderBook{}, err
+	}
+
+	return orderBook, nil
+}
+
+// storedOrderBook is an order book as stored in Redis. The timestamp is repeated as a number, so it can be compared
+// in Lua.
+type storedOrderBook struct {
+	entities.OrderBook
+	TimestampMicros int64
+}
+
+// UpdateOrderBookIfNewer stores an order book in Redis, unless the stored order book has a later timestamp.
+// The check and write are done atomically by a Lua script.
+func (c *Client) UpdateOrderBookIfNewer(ctx context.Context, orderBook entities.OrderBook) error {
+	orderBookData, err := json.Marshal(storedOrderBook{
+		OrderBook:       orderBook,
+		TimestampMicros: orderBook.Timestamp.UnixMicro(),
+	})
 	if err != nil {
 		return err
 	}
 
+	redisKey := "orderbook:" + string(orderBook.Exchange) + ":" + orderBook.TradingPair
+
+	// Expires with the market, a book that old can't be traded on
+	written, err := updateIfNewerScript.Run(ctx, c.rc, []string{redisKey},
+		orderBookData, orderBook.Timestamp.UnixMicro(), c.marketTTL.Milliseconds()).Int()
+	if err != nil {
+		return err
+	}
+
+	if written == 0 {
+		return fmt.Errorf("%w: current order book is newer than the provided order book", arberrors.ErrInvalidMarketTimestamp)
+	}
+
 	return nil
 }
@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/peterstirrup/arbenheimer/internal/outbound/redis")
+
+// tracingHook records a span for every command sent to Redis.
+type tracingHook struct{}
+
+func (tracingHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (tracingHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, span := startSpan(ctx, cmd.Name())
+		defer span.End()
+
+		err := next(ctx, cmd)
+		recordError(span, err)
+
+		return err
+	}
+}
+
+func (tracingHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, span := startSpan(ctx, "pipeline")
+		defer span.End()
+
+		span.SetAttributes(attribute.Int("db.redis.num_cmd", len(cmds)))
+
+		err := next(ctx, cmds)
+		recordError(span, err)
+
+		return err
+	}
+}
+
+func startSpan(ctx context.Context, command string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "redis."+command,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "redis"),
+			attribute.String("db.operation.name", command),
+		),
+	)
+}
+
+// recordError marks the span as failed, ignoring the same non-failures as observeCommand.
+func recordError(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, redis.Nil) && !redis.HasErrorPrefix(err, "NOSCRIPT") {
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -1,5 +1,271 @@
 package test
 
-/*
-	Implement me!
-*/
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/peterstirrup/arbenheimer/internal/domain/entities"
+	"github.com/peterstirrup/arbenheimer/internal/domain/usecases"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/server"
+	"github.com/peterstirrup/arbenheimer/internal/inbound/server/pb"
+	"github.com/peterstirrup/arbenheimer/internal/outbound/memory"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	ctx      = context.Background()
+	testTime = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	marketBinance = entities.Market{
+		Exchange:        entities.ExchangeBinance,
+		TradingPair:     "BTC/USDT",
+		BestBuyPrice:    decimal.NewFromFloat(69000),
+		BestSellPrice:   decimal.NewFromFloat(70000),
+		LastTradedPrice: decimal.NewFromFloat(69500),
+		Timestamp:       testTime,
+		Volume24hr:      1000000,
+	}
+
+	instrumentBinance = entities.Instrument{
+		Exchange:    entities.ExchangeBinance,
+		TradingPair: "BTC/USDT",
+		Status:      entities.ListingStatusTrading,
+		TickSize:    decimal.RequireFromString("0.01"),
+		StepSize:    decimal.RequireFromString("0.00001"),
+		MinQuantity: decimal.RequireFromString("0.00001"),
+		MaxQuantity: decimal.RequireFromString("9000"),
+		MinNotional: decimal.RequireFromString("5"),
+	}
+)
+
+// instrumentSource returns the same instruments every time, as an exchange's REST API would.
+type instrumentSource struct {
+	exchange    entities.Exchange
+	instruments []entities.Instrument
+}
+
+func (s instrumentSource) Exchange() entities.Exchange { return s.exchange }
+
+func (s instrumentSource) GetInstruments(context.Context) ([]entities.Instrument, error) {
+	return s.instruments, nil
+}
+
+type setupIntegrationTestConfig struct {
+	client pb.ArbenheimerServiceClient
+	market *usecases.Market
+}
+
+// setupTest runs the gRPC server in process, backed by the in-memory store.
+// Markets are updated through the use cases, as the exchange updaters do.
+func setupTest(t *testing.T) *setupIntegrationTestConfig {
+	mc := memory.NewClient(memory.Config{})
+
+	u := usecases.NewMarket(usecases.MarketConfig{
+		Broker:       mc,
+		Candles:      mc,
+		Store:        mc,
+		TimeNow:      func() time.Time { return testTime },
+		TradingPairs: []string{"BTC/USDT"},
+	})
+
+	instruments := usecases.NewInstruments(usecases.InstrumentsConfig{
+		Sources: []usecases.InstrumentSource{
+			instrumentSource{exchange: entities.ExchangeBinance, instruments: []entities.Instrument{instrumentBinance}},
+		},
+	})
+	require.NoError(t, instruments.Refresh(ctx))
+
+	lis := bufconn.Listen(1024 * 1024)
+	gs := grpc.NewServer()
+	pb.RegisterArbenheimerServiceServer(gs, server.NewServer(server.Config{
+		InstrumentUseCases: instruments,
+		MarketUseCases:     u,
+	}))
+
+	go func() {
+		_ = gs.Serve(lis)
+	}()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return &setupIntegrationTestConfig{
+		client: pb.NewArbenheimerServiceClient(conn),
+		market: u,
+	}
+}
+
+func TestGetMarket(t *testing.T) {
+	cfg := setupTest(t)
+
+	require.NoError(t, cfg.market.UpdateMarket(ctx, marketBinance))
+
+	resp, err := cfg.client.GetMarket(ctx, &pb.GetMarketRequest{TradingPair: "BTC/USDT"})
+	require.NoError(t, err)
+	require.Len(t, resp.Markets, 1)
+	require.Equal(t, "binance", resp.Markets[0].Exchange)
+	require.Equal(t, "69000", resp.Markets[0].BestBuyPrice)
+	require.Equal(t, "70000", resp.Markets[0].BestSellPrice)
+	require.Equal(t, time.Duration(0), resp.Markets[0].QuoteAge.AsDuration())
+	require.False(t, resp.Markets[0].Stale)
+}
+
+func TestGetMarket_Errors(t *testing.T) {
+	t.Run("market not found, returns NotFound with the trading pair", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		_, err := cfg.client.GetMarket(ctx, &pb.GetMarketRequest{TradingPair: "XMR/USDT"})
+		st, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.NotFound, st.Code())
+		require.Len(t, st.Details(), 1)
+
+		info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+		require.True(t, ok)
+		require.Equal(t, "MARKET_NOT_FOUND", info.Reason)
+		require.Equal(t, "XMR/USDT", info.Metadata["trading_pair"])
+	})
+
+	t.Run("aliased trading pair, returns InvalidArgument", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		_, err := cfg.client.GetMarket(ctx, &pb.GetMarketRequest{TradingPair: "XBT/USDT"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+		require.Contains(t, status.Convert(err).Message(), "e.g. BTC/USDT")
+	})
+
+	t.Run("invalid trading pair, returns InvalidArgument with the field", func(t *testing.T) {
+		cfg := setupTest(t)
+
+		_, err := cfg.client.GetMarket(ctx, &pb.GetMarketRequest{TradingPair: "btc-usdt"})
+		st, ok := status.FromError(err)
+		require.True(t, ok)
+		require.Equal(t, codes.InvalidArgument, st.Code())
+		require.Len(t, st.Details(), 1)
+
+		badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+		require.True(t, ok)
+		require.Equal(t, "trading_pair", badRequest.FieldViolations[0].Field)
+	})
+}
+
+func TestGetMarkets(t *testing.T) {
+	cfg := setupTest(t)
+
+	ethKuCoin := marketBinance
+	ethKuCoin.Exchange = entities.ExchangeKuCoin
+	ethKuCoin.TradingPair = "ETH/USDT"
+
+	require.NoError(t, cfg.market.UpdateMarket(ctx, marketBinance))
+	require.NoError(t, cfg.market.UpdateMarket(ctx, ethKuCoin))
+
+	resp, err := cfg.client.GetMarkets(ctx, &pb.GetMarketsRequest{TradingPairs: []string{"ETH/USDT", "XMR/USDT", "BTC/USDT"}})
+	require.NoError(t, err)
+	require.Len(t, resp.Markets, 2)
+	require.Equal(t, "ETH/USDT", resp.Markets[0].TradingPair)
+	require.Equal(t, "kucoin", resp.Markets[0].Exchange)
+	require.Equal(t, "BTC/USDT", resp.Markets[1].TradingPair)
+	require.Equal(t, "binance", resp.Markets[1].Exchange)
+}
+
+func TestGetCandles(t *testing.T) {
+	cfg := setupTest(t)
+
+	next := marketBinance
+	next.Timestamp = testTime.Add(30 * time.Second)
+	next.LastTradedPrice = decimal.NewFromFloat(69700)
+	// Trades leaving the 24hr window don't take volume off the candle
+	next.Volume24hr = marketBinance.Volume24hr - 5000
+
+	trade := entities.Trade{
+		TradingPair: "BTC/USDT",
+		Exchange:    entities.ExchangeBinance,
+		ID:          1,
+		Price:       decimal.NewFromFloat(69600),
+		Quantity:    decimal.NewFromFloat(0.1),
+		Timestamp:   testTime.Add(10 * time.Second),
+	}
+
+	require.NoError(t, cfg.market.UpdateMarket(ctx, marketBinance))
+	require.NoError(t, cfg.market.AddTrade(ctx, trade))
+	// A redundant updater's copy of the trade
+	require.NoError(t, cfg.market.AddTrade(ctx, trade))
+	require.NoError(t, cfg.market.UpdateMarket(ctx, next))
+
+	resp, err := cfg.client.GetCandles(ctx, &pb.GetCandlesRequest{
+		TradingPair: "BTC/USDT",
+		Exchange:    "binance",
+		Interval:    "1m",
+		From:        timestamppb.New(testTime),
+		To:          timestamppb.New(testTime),
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Candles, 1)
+	require.Equal(t, "69500", resp.Candles[0].Open)
+	require.Equal(t, "69700", resp.Candles[0].High)
+	require.Equal(t, "69700", resp.Candles[0].Close)
+	require.Equal(t, "6960", resp.Candles[0].Volume)
+}
+
+func TestGetInstruments(t *testing.T) {
+	cfg := setupTest(t)
+
+	resp, err := cfg.client.GetInstruments(ctx, &pb.GetInstrumentsRequest{TradingPair: "BTC/USDT"})
+	require.NoError(t, err)
+	require.Len(t, resp.Instruments, 1)
+	require.Equal(t, "binance", resp.Instruments[0].Exchange)
+	require.Equal(t, "trading", resp.Instruments[0].Status)
+	require.Equal(t, "0.01", resp.Instruments[0].TickSize)
+	require.Equal(t, "0.00001", resp.Instruments[0].StepSize)
+	require.Equal(t, "5", resp.Instruments[0].MinNotional)
+
+	_, err = cfg.client.GetInstruments(ctx, &pb.GetInstrumentsRequest{TradingPair: "BTC/USDT", Exchange: "kucoin"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestStreamMarkets(t *testing.T) {
+	cfg := setupTest(t)
+
+	streamCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	stream, err := cfg.client.StreamMarkets(streamCtx, &pb.StreamMarketsRequest{Exchanges: []string{"binance"}})
+	require.NoError(t, err)
+
+	// The stream may not be subscribed yet, so keep updating until the market arrives
+	go func() {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-streamCtx.Done():
+				return
+			case <-ticker.C:
+				_ = cfg.market.UpdateMarket(streamCtx, marketBinance)
+			}
+		}
+	}()
+
+	m, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, "BTC/USDT", m.TradingPair)
+	require.Equal(t, "69500", m.LastTradedPrice)
+}